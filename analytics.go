@@ -3,7 +3,11 @@ package main
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cloudfoundry/cli/cf/flags"
 )
@@ -54,84 +58,241 @@ type AnalyticDeploymentResult struct {
 }
 
 type AnalyticDeploymentConfiguration struct {
-	Memory    int `json:"memory"`
-	DiskQuota int `json:"diskQuota"`
-	Instances int `json:"instances"`
+	Memory    int                    `json:"memory" yaml:"memory"`
+	DiskQuota int                    `json:"diskQuota" yaml:"diskQuota"`
+	Instances int                    `json:"instances" yaml:"instances"`
+	Env       map[string]string      `json:"env,omitempty" yaml:"env"`
+	Config    map[string]interface{} `json:"config,omitempty" yaml:"config"`
 }
 
+var catalogIdPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isCatalogId reports whether s looks like a catalog entry ID rather than an analytic name.
+func isCatalogId(s string) bool {
+	return catalogIdPattern.MatchString(s)
+}
+
+// analyticsList returns the entries of all pages of the catalog.
 func (p *AnalyticsPlugin) analyticsList() []AnalyticCatalogEntry {
-	r, e := p.client.Get().Path("/api/v1/catalog/analytics").Do()
-	if e != nil {
-		fmt.Printf("Failed to get analytics list: %s\n", e)
-		panic(1)
+	var entries []AnalyticCatalogEntry
+	for page := 0; ; page++ {
+		r, e := p.client.Get().Path("/api/v1/catalog/analytics").AddQuery("pageNumber", strconv.Itoa(page)).Do()
+		if e != nil {
+			fmt.Printf("Failed to get analytics list: %s\n", e)
+			panic(1)
+		}
+		var analytics AnalyticCatalogEntryPage
+		r.JSON(&analytics)
+		entries = append(entries, analytics.Entries...)
+		if page+1 >= analytics.TotalPages || len(analytics.Entries) == 0 {
+			return entries
+		}
 	}
-	var analytics AnalyticCatalogEntryPage
-	r.JSON(&analytics)
-	return analytics.Entries
+}
+
+// executableLanguage guesses the supported language from the executable name.
+func executableLanguage(executablePath string) string {
+	switch {
+	case strings.HasSuffix(executablePath, ".zip"):
+		return "Python"
+	case strings.HasSuffix(executablePath, ".jar"):
+		return "Java"
+	}
+	return ""
+}
+
+// splitAnalyticRef splits an analytic reference of the form name[@version].
+func splitAnalyticRef(ref string) (name, version string) {
+	if i := strings.LastIndex(ref, "@"); i > 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
 }
 
 func (p *AnalyticsPlugin) analyticId(analyticName string) string {
-	analyticId := ""
+	id, ok := p.findAnalyticId(analyticName)
+	if !ok {
+		fmt.Printf("Analytic %s not found\n", analyticName)
+		panic(1)
+	}
+	return id
+}
+
+// findAnalyticId resolves an analytic ID or name[@version] like analyticId
+// but reports whether the analytic exists instead of failing. IDs are passed
+// through without checking, ambiguous names still fail.
+func (p *AnalyticsPlugin) findAnalyticId(analyticName string) (string, bool) {
+	if isCatalogId(analyticName) {
+		return analyticName, true
+	}
+	if id, ok := p.cachedAnalyticId(analyticName); ok {
+		return id, true
+	}
+	name, version := splitAnalyticRef(analyticName)
+	var matches, exact []AnalyticCatalogEntry
+	ids := make(map[string]string)
+	duplicates := make(map[string]bool)
 	for _, analytic := range p.analyticsList() {
 		if analytic.Name == analyticName {
-			analyticId = analytic.Id
-			break
+			exact = append(exact, analytic)
+		}
+		if analytic.Name == name && (version == "" || analytic.Version == version) {
+			matches = append(matches, analytic)
+		}
+		for _, ref := range []string{analytic.Name, analytic.Name + "@" + analytic.Version} {
+			if _, ok := ids[ref]; ok {
+				duplicates[ref] = true
+			}
+			ids[ref] = analytic.Id
 		}
 	}
-	if analyticId == "" {
-		fmt.Printf("Analytic %s not found\n", analyticName)
+	for ref := range duplicates {
+		delete(ids, ref)
+	}
+	p.cacheAnalyticIds(ids)
+	// Names may contain @, so a whole-name match wins over name@version.
+	if len(exact) > 0 {
+		matches = exact
+	}
+	if len(matches) == 0 {
+		return "", false
+	}
+	if len(matches) > 1 {
+		fmt.Printf("Analytic name %s is ambiguous, use name@version or one of the IDs instead:\n", analyticName)
+		for _, analytic := range matches {
+			fmt.Printf("  %s (version %s)\n", analytic.Id, analytic.Version)
+		}
+		panic(1)
+	}
+	return matches[0].Id, true
+}
+
+func (p *AnalyticsPlugin) analyticEntry(analyticName string) AnalyticCatalogEntry {
+	var entry AnalyticCatalogEntry
+	r, e := p.client.Get().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s", p.analyticId(analyticName))).Do()
+	if e = responseError(r, e); e == nil {
+		e = r.JSON(&entry)
+	}
+	if e != nil {
+		fmt.Printf("Failed to get analytic %s: %s\n", analyticName, e)
 		panic(1)
 	}
-	return analyticId
+	return entry
 }
 
-func (p *AnalyticsPlugin) listAnalytics() {
+func (p *AnalyticsPlugin) listAnalytics(wide bool, meta []string) {
+	filter, err := parseKeyValues(meta)
+	if err != nil {
+		fmt.Println(err)
+		panic(1)
+	}
 	p.ui.Say("Getting analytics list...")
 	analytics := p.analyticsList()
 	p.ui.Ok()
-	table := p.ui.Table([]string{"Name", "Version", "Taxonomy Location", "Author", "Description"})
+	headers := []string{"Name", "Version", "State", "Taxonomy Location", "Author", "Description"}
+	if wide {
+		headers = append([]string{"ID"}, headers...)
+	}
+	table := p.ui.Table(headers)
 	for _, analytic := range analytics {
-		table.Add(analytic.Name,
+		if !metadataMatches(analytic.CustomMetadata, filter) {
+			continue
+		}
+		row := []string{analytic.Name,
 			analytic.Version,
+			analytic.State,
 			analytic.TaxonomyLocation,
 			analytic.Author,
 			analytic.Description,
-		)
+		}
+		if wide {
+			row = append([]string{analytic.Id}, row...)
+		}
+		table.Add(row...)
+	}
+	table.Print()
+}
+
+func (p *AnalyticsPlugin) showAnalytic(analyticName string) {
+	p.ui.Say("Getting analytic %s...", analyticName)
+	analytic := p.analyticEntry(analyticName)
+	p.ui.Ok()
+	table := p.ui.Table([]string{"", ""})
+	table.Add("ID:", analytic.Id)
+	table.Add("Name:", analytic.Name)
+	table.Add("Version:", analytic.Version)
+	table.Add("Author:", analytic.Author)
+	table.Add("Description:", analytic.Description)
+	table.Add("Language:", analytic.SupportedLanguage)
+	table.Add("Taxonomy Location:", analytic.TaxonomyLocation)
+	table.Add("State:", analytic.State)
+	table.Add("Created:", analytic.CreatedTimestamp)
+	table.Add("Updated:", analytic.UpdatedTimestamp)
+	table.Print()
+
+	values := metadataValues(analytic.CustomMetadata)
+	if len(values) == 0 {
+		return
+	}
+	p.ui.Say("\nCustom metadata:")
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	table = p.ui.Table([]string{"Key", "Value"})
+	for _, key := range keys {
+		table.Add(key, values[key])
 	}
 	table.Print()
 }
 
 func (p *AnalyticsPlugin) runAnalytic(analyticName, inputFilePath string) {
+	output, e := p.runAnalyticById(p.analyticId(analyticName), inputFilePath)
+	if e != nil {
+		fmt.Printf("Failed to run analytic: %s\n", e)
+		panic(1)
+	}
+	fmt.Println(output)
+}
+
+func (p *AnalyticsPlugin) runAnalyticById(analyticId, inputFilePath string) (string, error) {
 	input, err := os.Open(inputFilePath)
 	if err != nil {
-		fmt.Printf("Error accessing input file: %s\n", err)
-		panic(1)
+		return "", fmt.Errorf("error accessing input file: %s", err)
 	}
-	req := p.client.Post().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s/execution", p.analyticId(analyticName)))
+	defer input.Close()
+	req := p.client.Post().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s/execution", analyticId))
 	req = req.Body(input)
 	r, e := req.Do()
 	if e != nil {
-		fmt.Printf("Failed to run analytic: %s\n", e)
-		panic(1)
+		return "", e
 	}
-	fmt.Println(r.String())
+	return r.String(), nil
 }
 
 func (p *AnalyticsPlugin) validateAnalytic(analyticName, inputFilePath string) {
+	result, e := p.validateAnalyticById(p.analyticId(analyticName), inputFilePath)
+	if e != nil {
+		fmt.Printf("Failed to validate analytic: %s\n", e)
+		panic(1)
+	}
+	p.ui.Say(result.Message)
+}
+
+func (p *AnalyticsPlugin) validateAnalyticById(analyticId, inputFilePath string) (AnalyticValidationResult, error) {
+	var result AnalyticValidationResult
 	input, err := os.Open(inputFilePath)
 	if err != nil {
-		fmt.Printf("Error accessing input file: %s\n", err)
-		panic(1)
+		return result, fmt.Errorf("error accessing input file: %s", err)
 	}
-	analyticId := p.analyticId(analyticName)
+	defer input.Close()
 	req := p.client.Post().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s/validation", analyticId))
 	req = req.Body(input)
 	r, e := req.Do()
 	if e != nil {
-		fmt.Printf("Failed to validate analytic: %s\n", e)
-		panic(1)
+		return result, e
 	}
-	var result AnalyticValidationResult
 	e = r.JSON(&result)
 	validateUrl := fmt.Sprintf("/api/v1/catalog/analytics/%s/validation/%s", analyticId, result.ValidationRequestId)
 	r, e = p.client.Get().Path(validateUrl).Do()
@@ -140,24 +301,96 @@ func (p *AnalyticsPlugin) validateAnalytic(analyticName, inputFilePath string) {
 		if result.Status == "COMPLETED" || result.Status == "ERROR" {
 			break
 		}
+		time.Sleep(statusPollInterval)
 		r, e = p.client.Get().Path(validateUrl).Do()
 	}
-	if e != nil {
-		fmt.Printf("Failed to validate analytic: %s\n", e)
+	return result, e
+}
+
+// deleteAnalytic shows the entries, artifacts and deployments that will be
+// removed and asks for confirmation unless skipConfirm is set. Deployed
+// analytics are only deleted when force is set. With allVersions every version
+// with the same name is deleted, without resolving the name to a single
+// version first.
+func (p *AnalyticsPlugin) deleteAnalytic(analyticName string, skipConfirm, force, allVersions bool) {
+	var entries []AnalyticCatalogEntry
+	if allVersions {
+		name, _ := splitAnalyticRef(analyticName)
+		if isCatalogId(analyticName) {
+			name = p.analyticEntry(analyticName).Name
+		}
+		analytics := p.analyticsList()
+		for _, analytic := range analytics {
+			if analytic.Name == analyticName {
+				name = analyticName
+			}
+		}
+		for _, analytic := range analytics {
+			if analytic.Name == name {
+				entries = append(entries, analytic)
+			}
+		}
+		if len(entries) == 0 {
+			fmt.Printf("Analytic %s not found\n", name)
+			panic(1)
+		}
+	} else {
+		entries = []AnalyticCatalogEntry{p.analyticEntry(analyticName)}
+	}
+
+	table := p.ui.Table([]string{"Name", "Version", "ID", "State", "Artifacts"})
+	deployments := p.ui.Table([]string{"Name", "Version", "Deployment Request", "Status", "Created"})
+	hasDeployments := false
+	var deployed []string
+	for _, analytic := range entries {
+		var artifacts []string
+		for _, artifact := range p.artifactsList(analytic.Id) {
+			artifacts = append(artifacts, fmt.Sprintf("%s (%s)", artifact.Filename, artifact.Type))
+		}
+		table.Add(analytic.Name, analytic.Version, analytic.Id, analytic.State, strings.Join(artifacts, ", "))
+		for _, d := range p.deploymentsList(analytic.Id) {
+			deployments.Add(analytic.Name, analytic.Version, d.RequestId, d.Status, d.CreatedTimestamp)
+			hasDeployments = true
+		}
+		if analytic.State == "DEPLOYED" {
+			deployed = append(deployed, fmt.Sprintf("%s@%s", analytic.Name, analytic.Version))
+		}
+	}
+	table.Print()
+	if hasDeployments {
+		p.ui.Say("\nDeployments that will be removed:")
+		deployments.Print()
+	}
+	if len(deployed) > 0 && !force {
+		fmt.Printf("Refusing to delete deployed analytics %s, use --force to delete them anyway\n", strings.Join(deployed, ", "))
 		panic(1)
 	}
-	p.ui.Say(result.Message)
+	if !skipConfirm && !p.ui.Confirm(fmt.Sprintf("Really delete %d analytic(s) with all of their artifacts?", len(entries))) {
+		return
+	}
+	for _, analytic := range entries {
+		p.ui.Say("Deleting analytic %s@%s...", analytic.Name, analytic.Version)
+		if e := p.deleteAnalyticById(analytic.Id); e != nil {
+			fmt.Printf("Failed to delete analytic: %s\n", e)
+			panic(1)
+		}
+		p.ui.Ok()
+	}
 }
 
-func (p *AnalyticsPlugin) deleteAnalytic(analyticName string) {
-	_, e := p.client.Delete().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s", p.analyticId(analyticName))).Do()
-	if e != nil {
-		fmt.Printf("Failed to delete analytic: %s\n", e)
-		panic(1)
+func (p *AnalyticsPlugin) deleteAnalyticById(analyticId string) error {
+	r, e := p.client.Delete().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s", analyticId)).Do()
+	if e = responseError(r, e); e != nil {
+		return e
 	}
+	p.forgetAnalytic(analyticId)
+	return nil
 }
 
-func (p *AnalyticsPlugin) createAnalytic(name, executablePath, version, author, language, description, taxonomyLocation, metadata string) {
+// createAnalytic creates the catalog entry and uploads its executable. If the
+// upload fails the new entry is deleted again, unless rollback is disabled, in
+// which case the command to finish the upload is printed.
+func (p *AnalyticsPlugin) createAnalytic(name, executablePath, version, author, language, description, taxonomyLocation, metadata string, rollback bool) string {
 	analytic := AnalyticCatalogEntry{
 		Name:              name,
 		Author:            author,
@@ -167,53 +400,110 @@ func (p *AnalyticsPlugin) createAnalytic(name, executablePath, version, author,
 		TaxonomyLocation:  taxonomyLocation,
 		CustomMetadata:    metadata,
 	}
-	_, e := p.client.Post().Path("/api/v1/catalog/analytics").JSON(analytic).Do()
+	created, e := p.createCatalogEntry(analytic)
 	if e != nil {
 		fmt.Printf("Failed to create analytic: %s\n", e)
 		panic(1)
 	}
-	p.addArtifact(name, executablePath, "Executable", "")
+	e = p.uploadArtifact(created.Id, executablePath, "Executable", "")
+	if e == nil {
+		return created.Id
+	}
+	fmt.Printf("Failed to upload executable: %s\n", e)
+	if rollback {
+		re := p.deleteAnalyticById(created.Id)
+		if re == nil {
+			fmt.Printf("Analytic %s was removed from the catalog\n", name)
+			panic(1)
+		}
+		fmt.Printf("Failed to remove analytic %s: %s\n", name, re)
+	}
+	fmt.Printf("Analytic %s was created without executable (ID %s), to finish run:\n", name, created.Id)
+	fmt.Printf("  cf add-analytic-artifact %s %s -type Executable\n", created.Id, executablePath)
+	panic(1)
 }
 
-func (p *AnalyticsPlugin) analyticLogs(name string) {
-	r, e := p.client.Get().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s/logs", p.analyticId(name))).Do()
-	if e != nil {
-		fmt.Printf("Failed to get analytic logs: %s\n", e)
-		panic(1)
+func (p *AnalyticsPlugin) createCatalogEntry(analytic AnalyticCatalogEntry) (AnalyticCatalogEntry, error) {
+	var created AnalyticCatalogEntry
+	r, e := p.client.Post().Path("/api/v1/catalog/analytics").JSON(analytic).Do()
+	if e = responseError(r, e); e != nil {
+		return created, e
 	}
-	p.ui.Say(strings.Replace(r.String(), "(STD", "\r(STD", -1))
+	p.forgetAnalyticName(analytic.Name)
+	if r.JSON(&created) != nil || created.Id == "" {
+		return created, fmt.Errorf("unexpected response %s", r.String())
+	}
+	return created, nil
 }
 
-func (p *AnalyticsPlugin) deployAnalytic(name string, c flags.FlagContext) {
-	config := AnalyticDeploymentConfiguration{
-		c.Int("memory"),
-		c.Int("diskQuota"),
-		c.Int("instances"),
+func (p *AnalyticsPlugin) updateCatalogEntry(analytic AnalyticCatalogEntry) error {
+	analytic.State = ""
+	analytic.CreatedTimestamp = ""
+	analytic.UpdatedTimestamp = ""
+	r, e := p.client.Put().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s", analytic.Id)).JSON(analytic).Do()
+	if e = responseError(r, e); e != nil {
+		return e
 	}
-	analyticId := p.analyticId(name)
-	r, e := p.client.Post().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s/deployment", analyticId)).JSON(config).Do()
+	p.forgetAnalyticName(analytic.Name)
+	return nil
+}
+
+func (p *AnalyticsPlugin) deployAnalytic(name string, c flags.FlagContext) {
+	config := deploymentConfigFromFlags(c)
+	p.printDeploymentConfig(config)
+	p.ui.Say("Deploying analytic %s...", name)
+	result, e := p.deployAnalyticById(p.analyticId(name), config)
 	if e != nil {
 		fmt.Printf("Failed to deploy analytic: %s\n", e)
 		panic(1)
 	}
-	var result AnalyticDeploymentResult
-	e = r.JSON(&result)
-	if e != nil {
-		fmt.Printf("Failed to deploy analytic: %s\n", e)
+	p.ui.Say(result.Message)
+}
+
+// deploymentConfigFromFlags reads the configuration from the -config file,
+// overridden by -memory, -diskQuota, -instances and -env flags.
+func deploymentConfigFromFlags(c flags.FlagContext) AnalyticDeploymentConfiguration {
+	config := defaultDeploymentConfiguration
+	if c.String("config") != "" {
+		var err error
+		config, err = loadDeploymentConfig(c.String("config"))
+		if err != nil {
+			fmt.Printf("Loading deployment configuration failed: %s\n", err)
+			panic(1)
+		}
+	}
+	if c.Int("memory") > 0 {
+		config.Memory = c.Int("memory")
+	}
+	if c.Int("diskQuota") > 0 {
+		config.DiskQuota = c.Int("diskQuota")
+	}
+	if c.Int("instances") > 0 {
+		config.Instances = c.Int("instances")
+	}
+	env, err := parseKeyValues(c.StringSlice("env"))
+	if err != nil {
+		fmt.Println(err)
 		panic(1)
 	}
-	statusUrl := fmt.Sprintf("/api/v1/catalog/analytics/%s/deployment/%s", analyticId, result.RequestId)
-	r, e = p.client.Get().Path(statusUrl).Do()
-	for e == nil {
-		e = r.JSON(&result)
-		if result.Status == "COMPLETED" || result.Status == "ERROR" {
-			break
-		}
-		r, e = p.client.Get().Path(statusUrl).Do()
+	if config.Env == nil && len(env) > 0 {
+		config.Env = make(map[string]string)
+	}
+	for k, v := range env {
+		config.Env[k] = v
+	}
+	return config
+}
+
+func (p *AnalyticsPlugin) deployAnalyticById(analyticId string, config AnalyticDeploymentConfiguration) (AnalyticDeploymentResult, error) {
+	var result AnalyticDeploymentResult
+	r, e := p.client.Post().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s/deployment", analyticId)).JSON(config).Do()
+	if e != nil {
+		return result, e
 	}
+	e = r.JSON(&result)
 	if e != nil {
-		fmt.Printf("Failed to deploy analytic: %s\n", e)
-		panic(1)
+		return result, e
 	}
-	p.ui.Say(result.Message)
+	return p.waitForDeployment(analyticId, result)
 }
@@ -1,12 +1,13 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+
+	"gopkg.in/h2non/gentleman.v0"
 )
 
 type ArtifactList struct {
@@ -34,13 +35,21 @@ func (p *AnalyticsPlugin) artifactsList(analyticName string) []Artifact {
 }
 
 func (p *AnalyticsPlugin) artifactId(analyticName, artifactName string) string {
+	analyticId := p.analyticId(analyticName)
+	if id, ok := p.cachedArtifactId(analyticId, artifactName); ok {
+		return id
+	}
 	artifactId := ""
-	for _, artifact := range p.artifactsList(analyticName) {
-		if artifact.Filename == artifactName {
+	ids := make(map[string]string)
+	for _, artifact := range p.artifactsList(analyticId) {
+		if artifact.Filename == artifactName && artifactId == "" {
 			artifactId = artifact.Id
-			break
+		}
+		if _, ok := ids[artifact.Filename]; !ok {
+			ids[artifact.Filename] = artifact.Id
 		}
 	}
+	p.cacheArtifactIds(analyticId, ids)
 	if artifactId == "" {
 		fmt.Printf("Artifact %s not found\n", artifactName)
 		panic(1)
@@ -52,24 +61,62 @@ func (p *AnalyticsPlugin) artifactUrl(analyticName, artifactName string) string
 	return fmt.Sprintf("/api/v1/catalog/artifacts/%s/file", p.artifactId(analyticName, artifactName))
 }
 
-func (p *AnalyticsPlugin) getArtifact(analyticName, artifactName string) {
-	r, e := p.client.Get().Path(p.artifactUrl(analyticName, artifactName)).Do()
+// getArtifact downloads an artifact to outputPath, or to its file name in dir
+// if no path is given, or writes it to stdout. Existing files are only
+// overwritten when forced.
+func (p *AnalyticsPlugin) getArtifact(analyticName, artifactName, outputPath, dir string, force, stdout bool) {
+	if outputPath == "" {
+		outputPath = filepath.Join(dir, filepath.Base(artifactName))
+	}
+	if !stdout && !force {
+		if _, err := os.Stat(outputPath); err == nil {
+			fmt.Printf("File %s already exists, use --force to overwrite it\n", outputPath)
+			panic(1)
+		}
+	}
+	r, e := p.openArtifact(p.artifactId(analyticName, artifactName))
 	if e != nil {
 		fmt.Printf("Failed to get artifact: %s\n", e)
 		panic(1)
 	}
-	if r.StatusCode == 200 {
-		r.SaveToFile(artifactName)
-	} else {
-		fmt.Println(r.String())
+	if stdout {
+		if _, e = io.Copy(os.Stdout, r); e != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get artifact: %s\n", e)
+			panic(1)
+		}
+		return
+	}
+	if e = os.MkdirAll(filepath.Dir(outputPath), 0755); e == nil {
+		e = r.SaveToFile(outputPath)
+	}
+	if e != nil {
+		fmt.Printf("Failed to save artifact: %s\n", e)
+		panic(1)
 	}
+	p.ui.Say("Artifact %s saved to %s", artifactName, outputPath)
 }
-func (p *AnalyticsPlugin) deleteArtifact(analyticName, artifactName string) {
-	r, e := p.client.Delete().Path(p.artifactUrl(analyticName, artifactName)).Do()
+
+func (p *AnalyticsPlugin) deleteArtifact(analyticName, artifactName string, skipConfirm bool) {
+	analyticId := p.analyticId(analyticName)
+	artifactId := p.artifactId(analyticId, artifactName)
+	if !skipConfirm {
+		table := p.ui.Table([]string{"Filename", "Type", "Description", "Updated"})
+		for _, artifact := range p.artifactsList(analyticId) {
+			if artifact.Id == artifactId {
+				table.Add(artifact.Filename, artifact.Type, artifact.Description, artifact.UpdatedTimestamp)
+			}
+		}
+		table.Print()
+		if !p.ui.Confirm(fmt.Sprintf("Really delete artifact %s of analytic %s?", artifactName, analyticName)) {
+			return
+		}
+	}
+	r, e := p.client.Delete().Path(p.artifactUrl(analyticId, artifactName)).Do()
 	if e != nil {
 		fmt.Printf("Failed to delete artifact: %s\n", e)
 		panic(1)
 	}
+	p.forgetArtifacts(analyticId)
 	if r.StatusCode == 204 {
 		fmt.Println("The artifact was removed from the catalog.")
 	} else {
@@ -77,6 +124,15 @@ func (p *AnalyticsPlugin) deleteArtifact(analyticName, artifactName string) {
 	}
 }
 
+func (p *AnalyticsPlugin) deleteArtifactById(analyticId, artifactId string) error {
+	r, e := p.client.Delete().Path(fmt.Sprintf("/api/v1/catalog/artifacts/%s/file", artifactId)).Do()
+	if e = responseError(r, e); e != nil {
+		return e
+	}
+	p.forgetArtifacts(analyticId)
+	return nil
+}
+
 func (p *AnalyticsPlugin) listArtifacts(analyticName string) {
 	table := p.ui.Table([]string{"Filename", "Type", "Description"})
 	for _, artifact := range p.artifactsList(analyticName) {
@@ -86,28 +142,132 @@ func (p *AnalyticsPlugin) listArtifacts(analyticName string) {
 }
 
 func (p *AnalyticsPlugin) addArtifact(analyticName, artifactPath, artifactType, description string) {
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	writer.WriteField("catalogEntryId", p.analyticId(analyticName))
-	writer.WriteField("type", artifactType)
-	if description != "" {
-		writer.WriteField("description", description)
+	e := p.uploadArtifact(p.analyticId(analyticName), artifactPath, artifactType, description)
+	if e != nil {
+		fmt.Printf("Failed to upload artifact: %s\n", e)
+		panic(1)
+	}
+}
+
+// replaceArtifactById uploads a new version of an artifact and deletes the
+// previous artifacts with the same file name only once the upload shows up
+// in the analytic artifacts. Type and description default to the previous
+// artifact's.
+func (p *AnalyticsPlugin) replaceArtifactById(analyticId, artifactPath, artifactType, description string) error {
+	filename := filepath.Base(artifactPath)
+	previous := make(map[string]Artifact)
+	for _, artifact := range p.artifactsList(analyticId) {
+		if artifact.Filename != filename {
+			continue
+		}
+		previous[artifact.Id] = artifact
+		if artifactType == "" {
+			artifactType = artifact.Type
+		}
+		if description == "" {
+			description = artifact.Description
+		}
+	}
+	if artifactType == "" {
+		return fmt.Errorf("artifact %s doesn't exist yet, specify its type", filename)
+	}
+	if err := p.uploadArtifact(analyticId, artifactPath, artifactType, description); err != nil {
+		return err
 	}
+	uploaded := false
+	for _, artifact := range p.artifactsList(analyticId) {
+		if _, ok := previous[artifact.Id]; artifact.Filename == filename && !ok {
+			uploaded = true
+		}
+	}
+	if !uploaded {
+		return fmt.Errorf("uploaded artifact %s is not listed in the analytic artifacts, previous version kept", filename)
+	}
+	for id := range previous {
+		if err := p.deleteArtifactById(analyticId, id); err != nil {
+			return fmt.Errorf("new artifact %s uploaded but the previous version couldn't be removed: %s", filename, err)
+		}
+	}
+	return nil
+}
+
+func (p *AnalyticsPlugin) replaceArtifact(analyticName, artifactPath, artifactType, description string) {
+	filename := filepath.Base(artifactPath)
+	p.ui.Say("Replacing artifact %s of analytic %s...", filename, analyticName)
+	if e := p.replaceArtifactById(p.analyticId(analyticName), artifactPath, artifactType, description); e != nil {
+		fmt.Printf("Failed to replace artifact: %s\n", e)
+		panic(1)
+	}
+	p.ui.Ok()
+}
+
+func (p *AnalyticsPlugin) uploadArtifact(analyticId, artifactPath, artifactType, description string) error {
 	file, err := os.Open(artifactPath)
 	if err != nil {
-		fmt.Printf("Failed to upload artifact: %s\n", err)
-		panic(1)
+		return err
 	}
-	part, err := writer.CreateFormFile("file", filepath.Base(artifactPath))
+	defer file.Close()
+	info, err := file.Stat()
 	if err != nil {
-		fmt.Printf("Failed to upload artifact: %s\n", err)
-		panic(1)
+		return err
 	}
-	_, err = io.Copy(part, file)
-	writer.Close()
-	_, e := p.client.Post().Path("/api/v1/catalog/artifacts").Body(body).SetHeader("Content-Type", writer.FormDataContentType()).Do()
-	if e != nil {
-		fmt.Printf("Failed to upload artifact: %s\n", e)
-		panic(1)
+	return p.uploadArtifactContent(analyticId, filepath.Base(artifactPath), file, info.Size(), artifactType, description)
+}
+
+// uploadArtifactContent streams the multipart upload through a pipe so the
+// artifact is never held in memory. size is only used to report progress and
+// may be -1 if unknown.
+func (p *AnalyticsPlugin) uploadArtifactContent(analyticId, filename string, content io.Reader, size int64, artifactType, description string) error {
+	body, pipe := io.Pipe()
+	writer := multipart.NewWriter(pipe)
+	written := make(chan error, 1)
+	progress := newProgressReader(content, filename, size)
+	go func() {
+		err := writeArtifactForm(writer, analyticId, filename, progress, artifactType, description)
+		progress.finish()
+		pipe.CloseWithError(err)
+		written <- err
+	}()
+	r, e := p.client.Post().Path("/api/v1/catalog/artifacts").Body(body).SetHeader("Content-Type", writer.FormDataContentType()).Do()
+	body.Close()
+	if err := <-written; err != nil && err != io.ErrClosedPipe {
+		return err
+	}
+	if e = responseError(r, e); e != nil {
+		return e
+	}
+	p.forgetArtifacts(analyticId)
+	return nil
+}
+
+func writeArtifactForm(writer *multipart.Writer, analyticId, filename string, content io.Reader, artifactType, description string) error {
+	if err := writer.WriteField("catalogEntryId", analyticId); err != nil {
+		return err
+	}
+	if err := writer.WriteField("type", artifactType); err != nil {
+		return err
+	}
+	if description != "" {
+		if err := writer.WriteField("description", description); err != nil {
+			return err
+		}
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, content); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// openArtifact starts downloading an artifact, the returned response is
+// read to get the file content.
+func (p *AnalyticsPlugin) openArtifact(artifactId string) (*gentleman.Response, error) {
+	r, e := p.client.Get().Path(fmt.Sprintf("/api/v1/catalog/artifacts/%s/file", artifactId)).Do()
+	if e = responseError(r, e); e != nil {
+		return nil, e
 	}
+	return r, nil
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+)
+
+// Name to ID lookups are cached on disk for a short time so that consecutive
+// commands don't have to list the whole catalog again.
+const idCacheTTL = 5 * time.Minute
+
+type zoneIdCache struct {
+	Updated   time.Time                    `json:"updated"`
+	Analytics map[string]string            `json:"analytics"`
+	Artifacts map[string]map[string]string `json:"artifacts"`
+}
+
+type idCache struct {
+	Zones map[string]*zoneIdCache `json:"zones"`
+}
+
+func newZoneIdCache() *zoneIdCache {
+	return &zoneIdCache{
+		Updated:   time.Now(),
+		Analytics: make(map[string]string),
+		Artifacts: make(map[string]map[string]string),
+	}
+}
+
+// stripFlag removes a boolean flag from args, accepting both -flag and --flag.
+func stripFlag(args []string, name string) ([]string, bool) {
+	var rest []string
+	found := false
+	for _, arg := range args {
+		if arg == "-"+name || arg == "--"+name {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
+}
+
+func (p *AnalyticsPlugin) loadCache() {
+	p.cache = &idCache{Zones: make(map[string]*zoneIdCache)}
+	if p.noCache {
+		return
+	}
+	file, err := pluginFilePath("cf_predix_analytics_plugin_cache")
+	if err != nil {
+		return
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return
+	}
+	if json.Unmarshal(data, p.cache) != nil || p.cache.Zones == nil {
+		p.cache = &idCache{Zones: make(map[string]*zoneIdCache)}
+	}
+}
+
+func (p *AnalyticsPlugin) saveCache() {
+	if p.noCache || p.cache == nil {
+		return
+	}
+	file, err := pluginFilePath("cf_predix_analytics_plugin_cache")
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(p.cache)
+	if err != nil {
+		return
+	}
+	ioutil.WriteFile(file, data, 0644)
+}
+
+func (p *AnalyticsPlugin) cacheZone() *zoneIdCache {
+	if p.cache == nil {
+		p.loadCache()
+	}
+	zone := p.analyticsServiceGuid()
+	c, ok := p.cache.Zones[zone]
+	if !ok || time.Since(c.Updated) > idCacheTTL {
+		c = newZoneIdCache()
+		p.cache.Zones[zone] = c
+	}
+	return c
+}
+
+func (p *AnalyticsPlugin) cachedAnalyticId(analyticName string) (string, bool) {
+	id, ok := p.cacheZone().Analytics[analyticName]
+	return id, ok
+}
+
+// cacheAnalyticIds replaces the cached analytic IDs with ids, which is built
+// from a full catalog listing, so names that became ambiguous are dropped.
+func (p *AnalyticsPlugin) cacheAnalyticIds(ids map[string]string) {
+	p.cacheZone().Analytics = ids
+	p.saveCache()
+}
+
+func (p *AnalyticsPlugin) cachedArtifactId(analyticId, artifactName string) (string, bool) {
+	id, ok := p.cacheZone().Artifacts[analyticId][artifactName]
+	return id, ok
+}
+
+func (p *AnalyticsPlugin) cacheArtifactIds(analyticId string, ids map[string]string) {
+	p.cacheZone().Artifacts[analyticId] = ids
+	p.saveCache()
+}
+
+// forgetAnalyticName drops the cached ID of an analytic name, e.g. after a new
+// entry with that name was created.
+func (p *AnalyticsPlugin) forgetAnalyticName(analyticName string) {
+	delete(p.cacheZone().Analytics, analyticName)
+	p.saveCache()
+}
+
+// forgetAnalytic drops everything cached about a deleted catalog entry.
+func (p *AnalyticsPlugin) forgetAnalytic(analyticId string) {
+	c := p.cacheZone()
+	for name, id := range c.Analytics {
+		if id == analyticId {
+			delete(c.Analytics, name)
+		}
+	}
+	delete(c.Artifacts, analyticId)
+	p.saveCache()
+}
+
+func (p *AnalyticsPlugin) forgetArtifacts(analyticId string) {
+	delete(p.cacheZone().Artifacts, analyticId)
+	p.saveCache()
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fmt"
+	"strings"
+
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 	"gopkg.in/h2non/gentleman.v0"
@@ -66,6 +68,18 @@ func (p *AnalyticsPlugin) authToken() string {
 	return p.AuthToken
 }
 
+// responseError returns the transport error, or an error describing the
+// response if the catalog didn't accept the request.
+func responseError(r *gentleman.Response, e error) error {
+	if e != nil {
+		return e
+	}
+	if !r.Ok {
+		return fmt.Errorf("%d %s", r.StatusCode, strings.TrimSpace(r.String()))
+	}
+	return nil
+}
+
 func (p *AnalyticsPlugin) invalidAuth() bool {
 	r, e := p.client.Get().Path("/api/v1/catalog/taxonomy").Do()
 	return e != nil || r.StatusCode != 200
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+)
+
+// cloneAnalytic creates a new version of an analytic with the same metadata
+// and artifacts. Artifacts are streamed from the existing entry into the new
+// one. If executablePath is set it replaces the Executable artifacts. The
+// version has to match the configured version pattern.
+func (p *AnalyticsPlugin) cloneAnalytic(analyticName, version, executablePath string) {
+	source := p.analyticEntry(analyticName)
+	version = p.analyticVersion(source.Name, version, "", false, p.VersionPattern)
+	for _, analytic := range p.analyticsList() {
+		if analytic.Name == source.Name && analytic.Version == version {
+			fmt.Printf("Analytic %s@%s already exists\n", source.Name, version)
+			panic(1)
+		}
+	}
+
+	clone := source
+	clone.Id = ""
+	clone.Version = version
+	clone.State = ""
+	clone.CreatedTimestamp = ""
+	clone.UpdatedTimestamp = ""
+	p.ui.Say("Creating analytic %s@%s...", clone.Name, version)
+	created, e := p.createCatalogEntry(clone)
+	if e != nil {
+		fmt.Printf("Failed to create analytic: %s\n", e)
+		panic(1)
+	}
+	p.ui.Ok()
+
+	rollback := func(e error) {
+		fmt.Printf("Failed to copy artifacts: %s\n", e)
+		if re := p.deleteAnalyticById(created.Id); re != nil {
+			fmt.Printf("Failed to remove analytic %s@%s (ID %s): %s\n", clone.Name, version, created.Id, re)
+		} else {
+			fmt.Printf("Analytic %s@%s was removed from the catalog\n", clone.Name, version)
+		}
+		panic(1)
+	}
+
+	for _, artifact := range p.artifactsList(source.Id) {
+		if executablePath != "" && artifact.Type == "Executable" {
+			continue
+		}
+		p.ui.Say("Copying artifact %s...", artifact.Filename)
+		r, e := p.openArtifact(artifact.Id)
+		if e == nil {
+			size := int64(-1)
+			if r.RawResponse != nil {
+				size = r.RawResponse.ContentLength
+			}
+			e = p.uploadArtifactContent(created.Id, artifact.Filename, r, size, artifact.Type, artifact.Description)
+		}
+		if e != nil {
+			rollback(fmt.Errorf("%s: %s", artifact.Filename, e))
+		}
+		p.ui.Ok()
+	}
+	if executablePath != "" {
+		p.ui.Say("Uploading executable %s...", executablePath)
+		if e := p.uploadArtifact(created.Id, executablePath, "Executable", ""); e != nil {
+			rollback(e)
+		}
+		p.ui.Ok()
+	}
+}
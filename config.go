@@ -10,13 +10,20 @@ import (
 	"github.com/cloudfoundry/cli/cf/configuration/confighelpers"
 )
 
-func (p *AnalyticsPlugin) loadConfig() {
+func pluginFilePath(name string) (string, error) {
 	home, err := confighelpers.DefaultFilePath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(home), name), nil
+}
+
+func (p *AnalyticsPlugin) loadConfig() {
+	file, err := pluginFilePath("cf_predix_analytics_plugin")
 	if err != nil {
 		fmt.Printf("Loading config failed: %s\n", err)
 		panic(1)
 	}
-	file := filepath.Join(filepath.Dir(home), "cf_predix_analytics_plugin")
 	if _, err := os.Stat(file); os.IsNotExist(err) {
 		return
 	}
@@ -32,12 +39,11 @@ func (p *AnalyticsPlugin) loadConfig() {
 }
 
 func (p *AnalyticsPlugin) saveConfig() {
-	home, err := confighelpers.DefaultFilePath()
+	file, err := pluginFilePath("cf_predix_analytics_plugin")
 	if err != nil {
 		fmt.Printf("Saving config failed: %s\n", err)
 		panic(1)
 	}
-	file := filepath.Join(filepath.Dir(home), "cf_predix_analytics_plugin")
 	config, err := json.Marshal(p)
 	if err != nil {
 		fmt.Printf("Saving config failed: %s\n", err)
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+type AnalyticDeploymentList struct {
+	Deployments []AnalyticDeploymentResult `json:"deploymentRequests"`
+}
+
+var defaultDeploymentConfiguration = AnalyticDeploymentConfiguration{
+	Memory:    512,
+	DiskQuota: 1024,
+	Instances: 1,
+}
+
+// jsonValue converts values decoded from YAML, which uses
+// map[interface{}]interface{} for nested objects, into JSON compatible ones.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{})
+		for k, value := range v {
+			m[fmt.Sprint(k)] = jsonValue(value)
+		}
+		return m
+	case map[string]interface{}:
+		for k, value := range v {
+			v[k] = jsonValue(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = jsonValue(value)
+		}
+		return v
+	}
+	return v
+}
+
+func (c *AnalyticDeploymentConfiguration) normalize() {
+	for k, v := range c.Config {
+		c.Config[k] = jsonValue(v)
+	}
+}
+
+// UnmarshalYAML starts from the default configuration, so settings missing
+// from the YAML keep their defaults.
+func (c *AnalyticDeploymentConfiguration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain AnalyticDeploymentConfiguration
+	config := plain(defaultDeploymentConfiguration)
+	if err := unmarshal(&config); err != nil {
+		return err
+	}
+	*c = AnalyticDeploymentConfiguration(config)
+	c.normalize()
+	return nil
+}
+
+// sameDeploymentConfig compares configurations by their JSON encoding, as
+// numbers in the config decoded from JSON and from YAML differ in type.
+func sameDeploymentConfig(a, b AnalyticDeploymentConfiguration) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
+
+// loadDeploymentConfig reads a YAML deployment configuration, settings missing
+// from the file keep their defaults.
+func loadDeploymentConfig(path string) (AnalyticDeploymentConfiguration, error) {
+	config := defaultDeploymentConfiguration
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		return config, err
+	}
+	config.normalize()
+	return config, nil
+}
+
+func (p *AnalyticsPlugin) printDeploymentConfig(config AnalyticDeploymentConfiguration) {
+	table := p.ui.Table([]string{"", ""})
+	table.Add("Memory:", fmt.Sprintf("%d MB", config.Memory))
+	table.Add("Disk quota:", fmt.Sprintf("%d MB", config.DiskQuota))
+	table.Add("Instances:", fmt.Sprintf("%d", config.Instances))
+	var keys []string
+	for k := range config.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		table.Add("Env:", fmt.Sprintf("%s=%s", k, config.Env[k]))
+	}
+	if len(config.Config) > 0 {
+		data, _ := json.Marshal(config.Config)
+		table.Add("Config:", string(data))
+	}
+	table.Print()
+}
+
+// deploymentsList returns the deployment requests of an analytic, newest first.
+func (p *AnalyticsPlugin) deploymentsList(analyticId string) []AnalyticDeploymentResult {
+	r, e := p.client.Get().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s/deployment", analyticId)).Do()
+	if e = responseError(r, e); e != nil {
+		fmt.Printf("Failed to get analytic deployments: %s\n", e)
+		panic(1)
+	}
+	var deployments AnalyticDeploymentList
+	r.JSON(&deployments)
+	sort.SliceStable(deployments.Deployments, func(i, j int) bool {
+		a := parseCatalogTime(deployments.Deployments[i].CreatedTimestamp)
+		b := parseCatalogTime(deployments.Deployments[j].CreatedTimestamp)
+		return a.After(b)
+	})
+	return deployments.Deployments
+}
+
+// currentDeploymentConfig returns the configuration of the latest completed
+// deployment, or the defaults if the analytic was never deployed.
+func (p *AnalyticsPlugin) currentDeploymentConfig(analyticId string) AnalyticDeploymentConfiguration {
+	for _, deployment := range p.deploymentsList(analyticId) {
+		if deployment.Status != "COMPLETED" || deployment.InputConfigData == "" {
+			continue
+		}
+		var config AnalyticDeploymentConfiguration
+		if json.Unmarshal([]byte(deployment.InputConfigData), &config) == nil {
+			return config
+		}
+	}
+	return defaultDeploymentConfiguration
+}
+
+// scaleAnalytic redeploys an analytic changing only the given settings, a
+// zero value keeps the current one.
+func (p *AnalyticsPlugin) scaleAnalytic(analyticName string, instances, memory, diskQuota int) {
+	if instances <= 0 && memory <= 0 && diskQuota <= 0 {
+		fmt.Println("Specify at least one of -i, -m or -d")
+		panic(1)
+	}
+	analyticId := p.analyticId(analyticName)
+	config := p.currentDeploymentConfig(analyticId)
+	if instances > 0 {
+		config.Instances = instances
+	}
+	if memory > 0 {
+		config.Memory = memory
+	}
+	if diskQuota > 0 {
+		config.DiskQuota = diskQuota
+	}
+	p.ui.Say("Scaling analytic %s to %d instance(s), %d MB memory, %d MB disk...", analyticName, config.Instances, config.Memory, config.DiskQuota)
+	result, e := p.deployAnalyticById(analyticId, config)
+	if e != nil {
+		fmt.Printf("Failed to scale analytic: %s\n", e)
+		panic(1)
+	}
+	if result.Status == "ERROR" {
+		fmt.Printf("Failed to scale analytic: %s\n", result.Message)
+		panic(1)
+	}
+	p.ui.Ok()
+	p.ui.Say(result.Message)
+}
+
+func (p *AnalyticsPlugin) listDeployments(analyticName, requestId string) {
+	p.ui.Say("Getting deployments of analytic %s...", analyticName)
+	deployments := p.deploymentsList(p.analyticId(analyticName))
+	p.ui.Ok()
+	if requestId == "" {
+		table := p.ui.Table([]string{"Request ID", "Status", "Configuration", "Message", "Created", "Updated"})
+		for _, d := range deployments {
+			table.Add(d.RequestId, d.Status, d.InputConfigData, d.Message, d.CreatedTimestamp, d.UpdatedTimestamp)
+		}
+		table.Print()
+		return
+	}
+	for _, d := range deployments {
+		if d.RequestId != requestId {
+			continue
+		}
+		table := p.ui.Table([]string{"", ""})
+		table.Add("Request ID:", d.RequestId)
+		table.Add("Status:", d.Status)
+		table.Add("Configuration:", d.InputConfigData)
+		table.Add("Message:", d.Message)
+		table.Add("Created:", d.CreatedTimestamp)
+		table.Add("Updated:", d.UpdatedTimestamp)
+		table.Print()
+		p.ui.Say("\nResult:")
+		buffer := bytes.Buffer{}
+		if json.Indent(&buffer, []byte(d.Result), "", "   ") == nil {
+			p.ui.Say(buffer.String())
+		} else {
+			p.ui.Say(d.Result)
+		}
+		return
+	}
+	fmt.Printf("Deployment request %s not found\n", requestId)
+	panic(1)
+}
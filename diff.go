@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+func (p *AnalyticsPlugin) artifactChecksum(artifactId string) (string, error) {
+	r, e := p.openArtifact(artifactId)
+	if e != nil {
+		return "", e
+	}
+	h := sha256.New()
+	if _, e = io.Copy(h, r); e != nil {
+		return "", e
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (p *AnalyticsPlugin) artifactChecksums(artifacts []Artifact) map[string]string {
+	checksums := make(map[string]string)
+	for _, artifact := range artifacts {
+		sum, e := p.artifactChecksum(artifact.Id)
+		if e != nil {
+			fmt.Printf("Failed to get artifact %s: %s\n", artifact.Filename, e)
+			panic(1)
+		}
+		checksums[artifact.Filename] = sum
+	}
+	return checksums
+}
+
+func shortChecksum(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
+
+// diffLines returns a unified-style line diff of a and b based on their
+// longest common subsequence.
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var out []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		out = append(out, "+ "+b[j])
+	}
+	return out
+}
+
+// outputLines splits analytic output into lines, indenting JSON first so
+// that differences show up per field.
+func outputLines(output string) []string {
+	buffer := bytes.Buffer{}
+	if json.Indent(&buffer, []byte(output), "", "   ") == nil {
+		output = buffer.String()
+	}
+	return strings.Split(strings.TrimRight(output, "\n"), "\n")
+}
+
+func (p *AnalyticsPlugin) diffAnalytics(nameA, nameB, inputFilePath string) {
+	a := p.analyticEntry(nameA)
+	b := p.analyticEntry(nameB)
+
+	p.ui.Say("Comparing catalog entries...")
+	fields := []struct {
+		name string
+		a, b string
+	}{
+		{"Name", a.Name, b.Name},
+		{"Version", a.Version, b.Version},
+		{"Author", a.Author, b.Author},
+		{"Description", a.Description, b.Description},
+		{"Language", a.SupportedLanguage, b.SupportedLanguage},
+		{"Taxonomy Location", a.TaxonomyLocation, b.TaxonomyLocation},
+		{"Custom Metadata", a.CustomMetadata, b.CustomMetadata},
+		{"State", a.State, b.State},
+	}
+	table := p.ui.Table([]string{"Field", nameA, nameB})
+	differences := 0
+	for _, f := range fields {
+		if f.a != f.b {
+			table.Add(f.name, f.a, f.b)
+			differences++
+		}
+	}
+	if differences > 0 {
+		table.Print()
+	} else {
+		p.ui.Say("Catalog entries are identical")
+	}
+
+	p.ui.Say("Comparing artifacts...")
+	artifactsA := p.artifactsList(a.Id)
+	artifactsB := p.artifactsList(b.Id)
+	checksumsA := p.artifactChecksums(artifactsA)
+	checksumsB := p.artifactChecksums(artifactsB)
+	types := make(map[string][2]string)
+	for _, artifact := range artifactsA {
+		t := types[artifact.Filename]
+		t[0] = artifact.Type
+		types[artifact.Filename] = t
+	}
+	for _, artifact := range artifactsB {
+		t := types[artifact.Filename]
+		t[1] = artifact.Type
+		types[artifact.Filename] = t
+	}
+	var filenames []string
+	for filename := range types {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	table = p.ui.Table([]string{"Filename", "Type", "Checksum", "Status"})
+	for _, filename := range filenames {
+		t := types[filename]
+		sumA, inA := checksumsA[filename]
+		sumB, inB := checksumsB[filename]
+		status := "same"
+		switch {
+		case !inB:
+			status = "only in " + nameA
+		case !inA:
+			status = "only in " + nameB
+		case sumA != sumB || t[0] != t[1]:
+			status = "changed"
+		}
+		typ := t[0]
+		if t[0] != t[1] && inA && inB {
+			typ = t[0] + " -> " + t[1]
+		} else if !inA {
+			typ = t[1]
+		}
+		sum := shortChecksum(sumA)
+		if sumA != sumB && inA && inB {
+			sum = shortChecksum(sumA) + " -> " + shortChecksum(sumB)
+		} else if !inA {
+			sum = shortChecksum(sumB)
+		}
+		table.Add(filename, typ, sum, status)
+	}
+	table.Print()
+
+	if inputFilePath == "" {
+		return
+	}
+	p.ui.Say("Running both analytics with %s...", inputFilePath)
+	outputA, e := p.runAnalyticById(a.Id, inputFilePath)
+	if e != nil {
+		fmt.Printf("Failed to run analytic %s: %s\n", nameA, e)
+		panic(1)
+	}
+	outputB, e := p.runAnalyticById(b.Id, inputFilePath)
+	if e != nil {
+		fmt.Printf("Failed to run analytic %s: %s\n", nameB, e)
+		panic(1)
+	}
+	if outputA == outputB {
+		p.ui.Say("Outputs are identical")
+		return
+	}
+	p.ui.Say("--- %s\n+++ %s", nameA, nameB)
+	for _, line := range diffLines(outputLines(outputA), outputLines(outputB)) {
+		fmt.Println(line)
+	}
+}
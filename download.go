@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const artifactDownloadWorkers = 4
+
+type downloadedArtifact struct {
+	Artifact
+	Size int64 `json:"size"`
+}
+
+// saveArtifact downloads an artifact to path and checks that the whole file
+// was received.
+func (p *AnalyticsPlugin) saveArtifact(artifactId, path string) (int64, error) {
+	r, e := p.openArtifact(artifactId)
+	if e != nil {
+		return 0, e
+	}
+	file, e := os.Create(path)
+	if e != nil {
+		return 0, e
+	}
+	n, e := io.Copy(file, r)
+	if ce := file.Close(); e == nil {
+		e = ce
+	}
+	if e != nil {
+		return n, e
+	}
+	if r.RawResponse != nil && r.RawResponse.ContentLength >= 0 && n != r.RawResponse.ContentLength {
+		return n, fmt.Errorf("incomplete download, got %d of %d bytes", n, r.RawResponse.ContentLength)
+	}
+	return n, nil
+}
+
+// getArtifacts downloads all artifacts of an analytic into dir and describes
+// them in dir/manifest.json. Artifacts whose file names would collide with each
+// other or with the manifest are rejected, existing files are only overwritten
+// when forced.
+func (p *AnalyticsPlugin) getArtifacts(analyticName, dir string, force bool) {
+	artifacts := p.artifactsList(analyticName)
+	paths := make([]string, len(artifacts))
+	used := map[string]bool{"manifest.json": true}
+	var existing []string
+	for i, artifact := range artifacts {
+		filename := filepath.Base(artifact.Filename)
+		if used[filename] {
+			fmt.Printf("Artifact file name %s is used more than once or clashes with manifest.json, use get-analytic-artifact -o to download it\n", filename)
+			panic(1)
+		}
+		used[filename] = true
+		paths[i] = filepath.Join(dir, filename)
+	}
+	for _, path := range append(paths, filepath.Join(dir, "manifest.json")) {
+		if _, err := os.Stat(path); err == nil {
+			existing = append(existing, path)
+		}
+	}
+	if len(existing) > 0 && !force {
+		fmt.Printf("Files %s already exist, use --force to overwrite them\n", strings.Join(existing, ", "))
+		panic(1)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Printf("Failed to get artifacts: %s\n", err)
+		panic(1)
+	}
+
+	downloaded := make([]downloadedArtifact, len(artifacts))
+	failures := make([]error, len(artifacts))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < artifactDownloadWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				artifact := artifacts[i]
+				size, err := p.saveArtifact(artifact.Id, paths[i])
+				downloaded[i] = downloadedArtifact{artifact, size}
+				failures[i] = err
+			}
+		}()
+	}
+	p.ui.Say("Downloading %d artifact(s) of analytic %s...", len(artifacts), analyticName)
+	for i := range artifacts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	table := p.ui.Table([]string{"Filename", "Type", "Size", "Status"})
+	failed := 0
+	for i, artifact := range downloaded {
+		status := "ok"
+		if failures[i] != nil {
+			status = failures[i].Error()
+			failed++
+		}
+		table.Add(artifacts[i].Filename, artifacts[i].Type, fmt.Sprintf("%d", artifact.Size), status)
+	}
+	table.Print()
+	if failed > 0 {
+		fmt.Printf("Failed to download %d artifact(s)\n", failed)
+		panic(1)
+	}
+	if err := writeJSONFile(filepath.Join(dir, "manifest.json"), downloaded); err != nil {
+		fmt.Printf("Failed to write manifest: %s\n", err)
+		panic(1)
+	}
+	p.ui.Ok()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const statusPollInterval = 2 * time.Second
+
+func finalDeploymentStatus(status string) bool {
+	return status == "COMPLETED" || status == "ERROR"
+}
+
+// waitForDeployment polls the deployment request until it completes or fails.
+func (p *AnalyticsPlugin) waitForDeployment(analyticId string, result AnalyticDeploymentResult) (AnalyticDeploymentResult, error) {
+	if result.RequestId == "" || finalDeploymentStatus(result.Status) {
+		return result, nil
+	}
+	statusUrl := fmt.Sprintf("/api/v1/catalog/analytics/%s/deployment/%s", analyticId, result.RequestId)
+	r, e := p.client.Get().Path(statusUrl).Do()
+	for e == nil {
+		e = r.JSON(&result)
+		if finalDeploymentStatus(result.Status) {
+			break
+		}
+		time.Sleep(statusPollInterval)
+		r, e = p.client.Get().Path(statusUrl).Do()
+	}
+	return result, e
+}
+
+func (p *AnalyticsPlugin) undeployAnalyticById(analyticId string) (AnalyticDeploymentResult, error) {
+	var result AnalyticDeploymentResult
+	r, e := p.client.Delete().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s/deployment", analyticId)).Do()
+	if e = responseError(r, e); e != nil {
+		return result, e
+	}
+	if len(r.Bytes()) > 0 {
+		if e = r.JSON(&result); e != nil {
+			return result, e
+		}
+	}
+	return p.waitForDeployment(analyticId, result)
+}
+
+func (p *AnalyticsPlugin) undeployAnalytic(analyticName string) {
+	p.ui.Say("Undeploying analytic %s...", analyticName)
+	result, e := p.undeployAnalyticById(p.analyticId(analyticName))
+	if e != nil {
+		fmt.Printf("Failed to undeploy analytic: %s\n", e)
+		panic(1)
+	}
+	if result.Status == "ERROR" {
+		fmt.Printf("Failed to undeploy analytic: %s\n", result.Message)
+		panic(1)
+	}
+	p.ui.Ok()
+	if result.Message != "" {
+		p.ui.Say(result.Message)
+	}
+}
+
+// waitForState polls the catalog entry until it reaches state or the timeout
+// expires. A zero timeout waits forever.
+func (p *AnalyticsPlugin) waitForState(analyticName, state string, timeout time.Duration) {
+	analyticId := p.analyticId(analyticName)
+	deadline := time.Now().Add(timeout)
+	last := ""
+	for {
+		entry := p.analyticEntry(analyticId)
+		if entry.State != last {
+			p.ui.Say("Analytic %s is %s", analyticName, entry.State)
+			last = entry.State
+		}
+		if strings.EqualFold(entry.State, state) {
+			return
+		}
+		if timeout > 0 && time.Now().After(deadline) {
+			fmt.Printf("Timed out waiting for analytic %s to become %s\n", analyticName, state)
+			panic(1)
+		}
+		time.Sleep(statusPollInterval)
+	}
+}
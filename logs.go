@@ -0,0 +1,309 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/cli/cf/terminal"
+)
+
+const logsPollInterval = 2 * time.Second
+
+// Timestamp and instance are only recognized at the start of a record, after
+// the stream marker, so brackets in the message stay part of it.
+var (
+	logTimestampPattern = regexp.MustCompile(`^\s*(\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?)`)
+	logInstancePattern  = regexp.MustCompile(`^\s*\[([^\]]+)\]`)
+)
+
+type LogRecord struct {
+	Timestamp string `json:"timestamp,omitempty"`
+	Stream    string `json:"stream,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Message   string `json:"message"`
+	time      time.Time
+}
+
+type logOptions struct {
+	follow     bool
+	since      time.Duration
+	tail       int
+	stderrOnly bool
+	grep       *regexp.Regexp
+	json       bool
+	outputPath string
+	output     io.Writer
+}
+
+func (p *AnalyticsPlugin) fetchLogs(analyticId string) (string, error) {
+	r, e := p.client.Get().Path(fmt.Sprintf("/api/v1/catalog/analytics/%s/logs", analyticId)).Do()
+	if e = responseError(r, e); e != nil {
+		return "", e
+	}
+	return r.String(), nil
+}
+
+// splitLogLines splits the logs text, in which every record starts with a
+// (STDOUT) or (STDERR) marker, into lines.
+func splitLogLines(logs string) []string {
+	logs = strings.Replace(logs, "(STD", "\n(STD", -1)
+	var lines []string
+	for _, line := range strings.Split(logs, "\n") {
+		line = strings.TrimRight(line, "\r ")
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func parseLogRecord(line string) LogRecord {
+	record := LogRecord{}
+	rest := line
+	for _, stream := range []string{"STDOUT", "STDERR"} {
+		if strings.HasPrefix(rest, "("+stream+")") {
+			record.Stream = stream
+			rest = strings.TrimPrefix(rest, "("+stream+")")
+			break
+		}
+	}
+	if m := logTimestampPattern.FindStringSubmatch(rest); m != nil {
+		record.Timestamp = m[1]
+		record.time = parseCatalogTime(strings.Replace(m[1], " ", "T", 1))
+		rest = rest[len(m[0]):]
+	}
+	if m := logInstancePattern.FindStringSubmatch(rest); m != nil {
+		record.Instance = m[1]
+		rest = rest[len(m[0]):]
+	}
+	record.Message = strings.TrimSpace(rest)
+	return record
+}
+
+func parseLogRecords(logs string) []LogRecord {
+	var records []LogRecord
+	for _, line := range splitLogLines(logs) {
+		records = append(records, parseLogRecord(line))
+	}
+	return records
+}
+
+func (o logOptions) matches(record LogRecord) bool {
+	if o.stderrOnly && record.Stream != "STDERR" {
+		return false
+	}
+	return o.grep == nil || o.grep.MatchString(record.Message)
+}
+
+// recentLogRecords keeps the matching records logged within since and then
+// the last tail of them. Zero values disable the limits.
+func (o logOptions) recentLogRecords(records []LogRecord) []LogRecord {
+	var recent []LogRecord
+	cutoff := time.Now().Add(-o.since)
+	for _, record := range records {
+		if !o.matches(record) {
+			continue
+		}
+		if o.since > 0 && !record.time.IsZero() && record.time.Before(cutoff) {
+			continue
+		}
+		recent = append(recent, record)
+	}
+	if o.tail > 0 && len(recent) > o.tail {
+		recent = recent[len(recent)-o.tail:]
+	}
+	return recent
+}
+
+// format renders a record, stream prefixes are only colorized when printing
+// to stdout.
+func (o logOptions) format(record LogRecord) string {
+	if o.json {
+		data, _ := json.Marshal(record)
+		return string(data)
+	}
+	color := func(colorize func(string) string, s string) string {
+		if o.output != nil {
+			return s
+		}
+		return colorize(s)
+	}
+	var prefix []string
+	if record.Timestamp != "" {
+		prefix = append(prefix, record.Timestamp)
+	}
+	if record.Instance != "" {
+		prefix = append(prefix, color(terminal.LogAppHeaderColor, "["+record.Instance+"]"))
+	}
+	switch record.Stream {
+	case "STDOUT":
+		prefix = append(prefix, color(terminal.LogStdoutColor, "OUT"))
+	case "STDERR":
+		prefix = append(prefix, color(terminal.LogStderrColor, "ERR"))
+	}
+	return strings.Join(append(prefix, record.Message), " ")
+}
+
+func (o logOptions) print(record LogRecord) {
+	if o.output != nil {
+		fmt.Fprintln(o.output, o.format(record))
+	} else {
+		fmt.Println(o.format(record))
+	}
+}
+
+func (o logOptions) printAll(records []LogRecord) {
+	for _, record := range records {
+		o.print(record)
+	}
+}
+
+// analyticLogs prints the recent logs. When following, the logs endpoint is
+// polled and only the lines logged since the previous poll are printed until
+// interrupted.
+func (p *AnalyticsPlugin) analyticLogs(name string, options logOptions) {
+	analyticId := p.analyticId(name)
+	logs, e := p.fetchLogs(analyticId)
+	if e != nil {
+		fmt.Printf("Failed to get analytic logs: %s\n", e)
+		panic(1)
+	}
+	// The output file is only created once there are logs to write, so a
+	// failed fetch doesn't truncate it.
+	if options.outputPath != "" {
+		file, err := os.Create(options.outputPath)
+		if err != nil {
+			fmt.Printf("Failed to write logs: %s\n", err)
+			panic(1)
+		}
+		defer file.Close()
+		options.output = file
+	}
+	records := parseLogRecords(logs)
+	for _, record := range options.recentLogRecords(records) {
+		options.print(record)
+	}
+	if !options.follow {
+		return
+	}
+
+	previous := splitLogLines(logs)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	ticker := time.NewTicker(logsPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-interrupt:
+			return
+		case <-ticker.C:
+		}
+		logs, e := p.fetchLogs(analyticId)
+		if e != nil {
+			fmt.Printf("Failed to get analytic logs: %s\n", e)
+			continue
+		}
+		current := splitLogLines(logs)
+		for _, line := range newLogLines(previous, current) {
+			if record := parseLogRecord(line); options.matches(record) {
+				options.print(record)
+			}
+		}
+		previous = current
+	}
+}
+
+// newLogLines returns the lines of current after its longest overlap with the
+// end of previous. The logs endpoint returns a window of the latest lines, so
+// the overlap is what was already printed, repeated lines included.
+func newLogLines(previous, current []string) []string {
+	k := len(previous)
+	if len(current) < k {
+		k = len(current)
+	}
+	for ; k > 0; k-- {
+		if reflect.DeepEqual(previous[len(previous)-k:], current[:k]) {
+			return current[k:]
+		}
+	}
+	return current
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// writeLogsFile writes the logs text as returned by the catalog.
+func writeLogsFile(path, logs string, compress bool) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = file
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(file)
+		w = gz
+	}
+	_, err = io.WriteString(w, logs)
+	if gz != nil {
+		if e := gz.Close(); err == nil {
+			err = e
+		}
+	}
+	if e := file.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// archiveLogs saves the logs of all analytics matching name and metadata into
+// timestamped files in dir.
+func (p *AnalyticsPlugin) archiveLogs(dir string, name *regexp.Regexp, meta []string, compress bool) {
+	filter, err := parseKeyValues(meta)
+	if err != nil {
+		fmt.Println(err)
+		panic(1)
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		fmt.Printf("Failed to archive logs: %s\n", err)
+		panic(1)
+	}
+	timestamp := time.Now().Format("20060102T150405")
+	archived, failed := 0, 0
+	for _, analytic := range p.analyticsList() {
+		if (name != nil && !name.MatchString(analytic.Name)) || !metadataMatches(analytic.CustomMetadata, filter) {
+			continue
+		}
+		p.ui.Say("Archiving logs of analytic %s@%s...", analytic.Name, analytic.Version)
+		logs, e := p.fetchLogs(analytic.Id)
+		if e != nil {
+			fmt.Printf("Failed to get analytic logs: %s\n", e)
+			failed++
+			continue
+		}
+		filename := unsafeFilenameChars.ReplaceAllString(fmt.Sprintf("%s-%s-%s", analytic.Name, analytic.Version, timestamp), "_") + ".log"
+		if compress {
+			filename += ".gz"
+		}
+		if e = writeLogsFile(filepath.Join(dir, filename), logs, compress); e != nil {
+			fmt.Printf("Failed to archive logs: %s\n", e)
+			panic(1)
+		}
+		p.ui.Ok()
+		archived++
+	}
+	p.ui.Say("Archived logs of %d analytic(s) into %s", archived, dir)
+	if failed > 0 {
+		fmt.Printf("Failed to archive logs of %d analytic(s)\n", failed)
+		panic(1)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewLogLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous []string
+		current  []string
+		want     []string
+	}{
+		{"first poll", nil, []string{"a", "b"}, []string{"a", "b"}},
+		{"nothing new", []string{"a", "b"}, []string{"a", "b"}, nil},
+		{"appended", []string{"a", "b"}, []string{"a", "b", "c"}, []string{"c"}},
+		{"window moved", []string{"a", "b", "c"}, []string{"b", "c", "d", "e"}, []string{"d", "e"}},
+		{"repeated line", []string{"a", "a"}, []string{"a", "a", "a"}, []string{"a"}},
+		{"repeated line after window moved", []string{"x", "a"}, []string{"a", "a"}, []string{"a"}},
+		{"repeated block", []string{"a", "b", "a", "b"}, []string{"a", "b", "a", "b", "a", "b"}, []string{"a", "b"}},
+		{"no overlap", []string{"a", "b"}, []string{"c", "d"}, []string{"c", "d"}},
+		{"empty window", []string{"a"}, nil, nil},
+	}
+	for _, test := range tests {
+		got := newLogLines(test.previous, test.current)
+		if len(got) == 0 && len(test.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseLogRecord(t *testing.T) {
+	tests := []struct {
+		line string
+		want LogRecord
+	}{
+		{
+			"(STDOUT) 2017-03-01T10:15:30Z [App/0] started",
+			LogRecord{Timestamp: "2017-03-01T10:15:30Z", Stream: "STDOUT", Instance: "App/0", Message: "started"},
+		},
+		{
+			"(STDERR) 2017-03-01 10:15:30.123+0000 failed",
+			LogRecord{Timestamp: "2017-03-01 10:15:30.123+0000", Stream: "STDERR", Message: "failed"},
+		},
+		{
+			"(STDOUT) [main] INFO listening on [::]:8080",
+			LogRecord{Stream: "STDOUT", Instance: "main", Message: "INFO listening on [::]:8080"},
+		},
+		{
+			"(STDOUT) INFO [main] request took 2017-03-01T10:15:30Z",
+			LogRecord{Stream: "STDOUT", Message: "INFO [main] request took 2017-03-01T10:15:30Z"},
+		},
+		{
+			"2017-03-01T10:15:30Z INFO [worker] done",
+			LogRecord{Timestamp: "2017-03-01T10:15:30Z", Message: "INFO [worker] done"},
+		},
+		{
+			"plain message [with brackets]",
+			LogRecord{Message: "plain message [with brackets]"},
+		},
+	}
+	for _, test := range tests {
+		got := parseLogRecord(test.line)
+		got.time = test.want.time
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got %+v, want %+v", test.line, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+type ManifestArtifact struct {
+	Path        string `yaml:"path"`
+	Type        string `yaml:"type"`
+	Description string `yaml:"description"`
+}
+
+type ManifestAnalytic struct {
+	Name        string                           `yaml:"name"`
+	Version     string                           `yaml:"version"`
+	Author      string                           `yaml:"author"`
+	Description string                           `yaml:"description"`
+	Language    string                           `yaml:"language"`
+	Taxonomy    string                           `yaml:"taxonomy"`
+	Metadata    string                           `yaml:"metadata"`
+	Artifacts   []ManifestArtifact               `yaml:"artifacts"`
+	Deployment  *AnalyticDeploymentConfiguration `yaml:"deployment"`
+}
+
+type AnalyticsManifest struct {
+	Analytics []ManifestAnalytic `yaml:"analytics"`
+}
+
+// manifestAction is a single step needed to bring the catalog in line with
+// the manifest.
+type manifestAction struct {
+	Analytic string
+	Action   string
+	Details  string
+	run      func() error
+}
+
+func loadManifest(manifestPath string) AnalyticsManifest {
+	var manifest AnalyticsManifest
+	data, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		fmt.Printf("Loading manifest failed: %s\n", err)
+		panic(1)
+	}
+	if err = yaml.Unmarshal(data, &manifest); err != nil {
+		fmt.Printf("Loading manifest failed: %s\n", err)
+		panic(1)
+	}
+	dir := filepath.Dir(manifestPath)
+	for i, analytic := range manifest.Analytics {
+		if analytic.Name == "" {
+			fmt.Printf("Invalid manifest: analytic #%d has no name\n", i+1)
+			panic(1)
+		}
+		for j, artifact := range analytic.Artifacts {
+			if artifact.Path == "" || artifact.Type == "" {
+				fmt.Printf("Invalid manifest: artifacts of %s need a path and a type\n", analytic.Name)
+				panic(1)
+			}
+			if !filepath.IsAbs(artifact.Path) {
+				artifact.Path = filepath.Join(dir, artifact.Path)
+			}
+			if _, err := os.Stat(artifact.Path); err != nil {
+				fmt.Printf("Invalid manifest: %s\n", err)
+				panic(1)
+			}
+			manifest.Analytics[i].Artifacts[j] = artifact
+		}
+	}
+	return manifest
+}
+
+// findCatalogEntry looks up the entry matching the manifest analytic by name
+// and, if the manifest pins one, by version. An unversioned analytic matching
+// several versions is ambiguous and fails like analyticId does.
+func findCatalogEntry(entries []AnalyticCatalogEntry, analytic ManifestAnalytic) (AnalyticCatalogEntry, bool) {
+	var matches []AnalyticCatalogEntry
+	for _, entry := range entries {
+		if entry.Name == analytic.Name && (analytic.Version == "" || entry.Version == analytic.Version) {
+			matches = append(matches, entry)
+		}
+	}
+	if len(matches) > 1 {
+		fmt.Printf("Analytic %s in the manifest is ambiguous, add the version to use:\n", analytic.Name)
+		for _, entry := range matches {
+			fmt.Printf("  %s (version %s)\n", entry.Id, entry.Version)
+		}
+		panic(1)
+	}
+	if len(matches) == 0 {
+		return AnalyticCatalogEntry{}, false
+	}
+	return matches[0], true
+}
+
+func diffField(changes []string, entryField *string, name, value string) []string {
+	if value == "" || *entryField == value {
+		return changes
+	}
+	changes = append(changes, fmt.Sprintf("%s: %q -> %q", name, *entryField, value))
+	*entryField = value
+	return changes
+}
+
+func (p *AnalyticsPlugin) planManifest(manifest AnalyticsManifest) []manifestAction {
+	var actions []manifestAction
+	entries := p.analyticsList()
+	for _, analytic := range manifest.Analytics {
+		analytic := analytic
+		label := analytic.Name
+		if analytic.Version != "" {
+			label = fmt.Sprintf("%s@%s", analytic.Name, analytic.Version)
+		}
+		entry, exists := findCatalogEntry(entries, analytic)
+		analyticId := entry.Id
+		changed := false
+
+		remoteArtifacts := make(map[string]Artifact)
+		checksums := make(map[string]string)
+		if exists {
+			local := make(map[string]bool)
+			for _, artifact := range analytic.Artifacts {
+				local[filepath.Base(artifact.Path)] = true
+			}
+			var compared []Artifact
+			for _, artifact := range p.artifactsList(entry.Id) {
+				remoteArtifacts[artifact.Filename] = artifact
+				if local[artifact.Filename] {
+					compared = append(compared, artifact)
+				}
+			}
+			checksums = p.artifactChecksums(compared)
+			var changes []string
+			changes = diffField(changes, &entry.Author, "author", analytic.Author)
+			changes = diffField(changes, &entry.Description, "description", analytic.Description)
+			changes = diffField(changes, &entry.SupportedLanguage, "language", analytic.Language)
+			changes = diffField(changes, &entry.TaxonomyLocation, "taxonomy", analytic.Taxonomy)
+			changes = diffField(changes, &entry.CustomMetadata, "metadata", analytic.Metadata)
+			if len(changes) > 0 {
+				changed = true
+				updated := entry
+				actions = append(actions, manifestAction{label, "update", strings.Join(changes, ", "), func() error {
+					return p.updateCatalogEntry(updated)
+				}})
+			}
+		} else {
+			if analytic.Version == "" {
+				fmt.Printf("Invalid manifest: analytic %s doesn't exist yet and needs a version\n", analytic.Name)
+				panic(1)
+			}
+			changed = true
+			newEntry := AnalyticCatalogEntry{
+				Name:              analytic.Name,
+				Version:           analytic.Version,
+				Author:            analytic.Author,
+				Description:       analytic.Description,
+				SupportedLanguage: analytic.Language,
+				TaxonomyLocation:  analytic.Taxonomy,
+				CustomMetadata:    analytic.Metadata,
+			}
+			actions = append(actions, manifestAction{label, "create", "", func() error {
+				created, e := p.createCatalogEntry(newEntry)
+				analyticId = created.Id
+				return e
+			}})
+		}
+
+		for _, artifact := range analytic.Artifacts {
+			artifact := artifact
+			filename := filepath.Base(artifact.Path)
+			details := fmt.Sprintf("%s (%s)", filename, artifact.Type)
+			if _, ok := remoteArtifacts[filename]; ok {
+				sum, err := fileChecksum(artifact.Path)
+				if err != nil {
+					fmt.Printf("Failed to read artifact %s: %s\n", artifact.Path, err)
+					panic(1)
+				}
+				if sum == checksums[filename] {
+					continue
+				}
+				changed = true
+				actions = append(actions, manifestAction{label, "replace", details, func() error {
+					return p.replaceArtifactById(analyticId, artifact.Path, artifact.Type, artifact.Description)
+				}})
+				continue
+			}
+			changed = true
+			actions = append(actions, manifestAction{label, "upload", details, func() error {
+				return p.uploadArtifact(analyticId, artifact.Path, artifact.Type, artifact.Description)
+			}})
+		}
+
+		if analytic.Deployment != nil {
+			config := *analytic.Deployment
+			redeploy := changed || entry.State != "DEPLOYED"
+			if !redeploy {
+				redeploy = !sameDeploymentConfig(p.currentDeploymentConfig(entry.Id), config)
+			}
+			if redeploy {
+				details := fmt.Sprintf("memory %d MB, disk %d MB, %d instance(s)", config.Memory, config.DiskQuota, config.Instances)
+				actions = append(actions, manifestAction{label, "deploy", details, func() error {
+					result, e := p.deployAnalyticById(analyticId, config)
+					if e == nil && result.Status != "COMPLETED" {
+						e = fmt.Errorf("%s", result.Message)
+					}
+					return e
+				}})
+			}
+		}
+	}
+	return actions
+}
+
+func (p *AnalyticsPlugin) printManifestPlan(actions []manifestAction) {
+	if len(actions) == 0 {
+		p.ui.Say("Catalog is up to date with the manifest")
+		return
+	}
+	table := p.ui.Table([]string{"Analytic", "Action", "Details"})
+	for _, action := range actions {
+		table.Add(action.Analytic, action.Action, action.Details)
+	}
+	table.Print()
+}
+
+func (p *AnalyticsPlugin) analyticsPlan(manifestPath string) {
+	manifest := loadManifest(manifestPath)
+	p.ui.Say("Comparing %s with the catalog...", manifestPath)
+	actions := p.planManifest(manifest)
+	p.ui.Ok()
+	p.printManifestPlan(actions)
+}
+
+func (p *AnalyticsPlugin) analyticsApply(manifestPath string) {
+	manifest := loadManifest(manifestPath)
+	p.ui.Say("Comparing %s with the catalog...", manifestPath)
+	actions := p.planManifest(manifest)
+	p.ui.Ok()
+	p.printManifestPlan(actions)
+	for _, action := range actions {
+		p.ui.Say("%s %s %s", action.Action, action.Analytic, action.Details)
+		if e := action.run(); e != nil {
+			fmt.Printf("Failed to %s analytic %s: %s\n", action.Action, action.Analytic, e)
+			panic(1)
+		}
+		p.ui.Ok()
+	}
+}
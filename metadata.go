@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Custom metadata is stored by the catalog as a plain string. The plugin keeps
+// it as a JSON object so that analytics can be tagged with key/value pairs.
+
+func decodeMetadata(metadata string) map[string]interface{} {
+	values := make(map[string]interface{})
+	if metadata != "" && json.Unmarshal([]byte(metadata), &values) != nil {
+		values = map[string]interface{}{"metadata": metadata}
+	}
+	return values
+}
+
+func encodeMetadata(values map[string]interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// setMetadataValue sets key in the JSON object kept in custom metadata.
+// Metadata that isn't a JSON object is preserved under the "metadata" key.
+func setMetadataValue(metadata, key, value string) string {
+	values := decodeMetadata(metadata)
+	values[key] = value
+	return encodeMetadata(values)
+}
+
+// metadataValues returns the custom metadata as strings, non-string values
+// are shown as JSON.
+func metadataValues(metadata string) map[string]string {
+	values := make(map[string]string)
+	for key, value := range decodeMetadata(metadata) {
+		if s, ok := value.(string); ok {
+			values[key] = s
+		} else {
+			data, _ := json.Marshal(value)
+			values[key] = string(data)
+		}
+	}
+	return values
+}
+
+func parseKeyValues(pairs []string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid value %s, use key=value", pair)
+		}
+		values[kv[0]] = kv[1]
+	}
+	return values, nil
+}
+
+// buildMetadata merges raw metadata, the JSON object in file and key=value
+// pairs, later ones taking precedence.
+func buildMetadata(raw, file string, pairs []string) (string, error) {
+	if file == "" && len(pairs) == 0 {
+		return raw, nil
+	}
+	values := decodeMetadata(raw)
+	if file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		fromFile := make(map[string]interface{})
+		if err = json.Unmarshal(data, &fromFile); err != nil {
+			return "", fmt.Errorf("%s must contain a JSON object: %s", file, err)
+		}
+		for key, value := range fromFile {
+			values[key] = value
+		}
+	}
+	kv, err := parseKeyValues(pairs)
+	if err != nil {
+		return "", err
+	}
+	for key, value := range kv {
+		values[key] = value
+	}
+	return encodeMetadata(values), nil
+}
+
+func metadataMatches(metadata string, filter map[string]string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	values := metadataValues(metadata)
+	for key, value := range filter {
+		if v, ok := values[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
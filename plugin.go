@@ -3,7 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
-	"strings"
+	"regexp"
 	"time"
 
 	"github.com/cloudfoundry/cli/cf/flags"
@@ -16,12 +16,15 @@ import (
 )
 
 type AnalyticsPlugin struct {
-	ui            terminal.UI          `json:"-"`
-	client        *gentleman.Client    `json:"-"`
-	cliConnection plugin.CliConnection `json:"-"`
-	UaaGuid       string               `json:"uaa_guid"`
-	AnalyticsGuid string               `json:"analytics_guid"`
-	AuthToken     string               `json:"auth_token"`
+	ui             terminal.UI          `json:"-"`
+	client         *gentleman.Client    `json:"-"`
+	cliConnection  plugin.CliConnection `json:"-"`
+	cache          *idCache             `json:"-"`
+	noCache        bool                 `json:"-"`
+	UaaGuid        string               `json:"uaa_guid"`
+	AnalyticsGuid  string               `json:"analytics_guid"`
+	AuthToken      string               `json:"auth_token"`
+	VersionPattern string               `json:"version_pattern,omitempty"`
 }
 
 func main() {
@@ -38,6 +41,8 @@ func (p *AnalyticsPlugin) Run(cliConnection plugin.CliConnection, args []string)
 		return
 	}
 
+	args, p.noCache = stripFlag(args, "no-cache")
+
 	p.ui = terminal.NewUI(os.Stdin, os.Stdout, terminal.NewTeePrinter(os.Stdout), trace.NewWriterPrinter(os.Stdout, false))
 	p.cliConnection = cliConnection
 
@@ -57,63 +62,122 @@ func (p *AnalyticsPlugin) Run(cliConnection plugin.CliConnection, args []string)
 		}
 		p.addTaxonomy(args[1])
 	case "analytics":
-		p.listAnalytics()
+		fc := flags.New()
+		fc.NewBoolFlag("wide", "w", "Show analytic IDs")
+		fc.NewStringSliceFlag("meta", "", "Only list analytics with this custom metadata key=value, flag can be specified multiple times")
+		err := fc.Parse(args[1:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		p.listAnalytics(fc.Bool("wide"), fc.StringSlice("meta"))
+	case "analytic":
+		if len(args) < 2 {
+			fmt.Println("usage cf analytic <Analytic name[@version]|ID>")
+			panic(1)
+		}
+		p.showAnalytic(args[1])
 	case "create-analytic":
 		if len(args) < 3 {
 			fmt.Println("usage cf create-analytic <Analytic name> <executable path>")
 			panic(1)
 		}
-		var language string
+		language := executableLanguage(args[2])
 		t := time.Now()
 		defaultVersion := fmt.Sprintf("V1-%s", t.Format("Jan-2"))
 		defaultAuthor, _ := cliConnection.Username()
-		switch {
-		case strings.HasSuffix(args[2], ".zip"):
-			language = "Python"
-		case strings.HasSuffix(args[2], ".jar"):
-			language = "Java"
-		}
 		fc := flags.New()
-		fc.NewStringFlagWithDefault("version", "v", "Analytic version", defaultVersion)
+		fc.NewStringFlag("version", "v", "Analytic version, defaults to V1-<month>-<day>")
 		fc.NewStringFlagWithDefault("author", "a", "Analytic author", defaultAuthor)
 		fc.NewStringFlagWithDefault("language", "l", "Analytic supported language", language)
 		fc.NewStringFlag("description", "d", "Analytic description")
 		fc.NewStringFlag("taxonomy", "t", "Analytic taxonomy location")
 		fc.NewStringFlag("metadata", "m", "Analytic custom metadata")
+		fc.NewStringSliceFlag("meta", "", "Custom metadata key=value, flag can be specified multiple times")
+		fc.NewStringFlag("meta-file", "", "JSON file with custom metadata")
+		fc.NewBoolFlag("no-rollback", "", "Keep the analytic if the executable upload fails")
+		fc.NewStringFlag("bump", "b", "Increment the latest semantic version of the analytic (major|minor|patch)")
+		fc.NewBoolFlag("git-version", "g", "Use git describe of the current directory as version")
+		fc.NewStringFlagWithDefault("version-pattern", "", "Regular expression the version has to match", p.VersionPattern)
 		err := fc.Parse(args[3:]...)
 		if err != nil {
 			fmt.Println(err)
 			panic(1)
 		}
+		versionSources := 0
+		for _, set := range []bool{fc.IsSet("version"), fc.IsSet("bump"), fc.Bool("git-version")} {
+			if set {
+				versionSources++
+			}
+		}
+		if versionSources > 1 {
+			fmt.Println("Only one of -version, -bump and -git-version can be used")
+			panic(1)
+		}
+		metadata, err := buildMetadata(fc.String("metadata"), fc.String("meta-file"), fc.StringSlice("meta"))
+		if err != nil {
+			fmt.Printf("Invalid custom metadata: %s\n", err)
+			panic(1)
+		}
+		version := defaultVersion
+		if fc.IsSet("version") {
+			version = fc.String("version")
+		}
+		version = p.analyticVersion(args[1], version, fc.String("bump"), fc.Bool("git-version"), fc.String("version-pattern"))
 		p.createAnalytic(args[1],
 			args[2],
-			fc.String("version"),
+			version,
 			fc.String("author"),
 			fc.String("language"),
 			fc.String("description"),
 			fc.String("taxonomy"),
-			fc.String("metadata"),
+			metadata,
+			!fc.Bool("no-rollback"),
 		)
 	case "analytic-artifacts":
 		if len(args) < 2 {
-			fmt.Println("usage cf analytic-artifacts <Analytic name>")
+			fmt.Println("usage cf analytic-artifacts <Analytic name[@version]|ID>")
 			panic(1)
 		}
 		p.listArtifacts(args[1])
 	case "get-analytic-artifact":
 		if len(args) < 3 {
-			fmt.Printf("usage cf get-analytic-artifact <Analytic name> <file name>\n", args[0])
+			fmt.Println("usage cf get-analytic-artifact <Analytic name[@version]|ID> <file name> [-o path | --dir dir] [--force] [--stdout]")
 			panic(1)
 		}
-		p.getArtifact(args[1], args[2])
+		fc := flags.New()
+		fc.NewStringFlag("output", "o", "Path to save the artifact to")
+		fc.NewStringFlagWithDefault("dir", "", "Directory to save the artifact to", ".")
+		fc.NewBoolFlag("force", "f", "Overwrite existing files")
+		fc.NewBoolFlag("stdout", "", "Write the artifact to stdout")
+		err := fc.Parse(args[3:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		p.getArtifact(args[1], args[2], fc.String("output"), fc.String("dir"), fc.Bool("force"), fc.Bool("stdout"))
+	case "get-analytic-artifacts":
+		if len(args) < 2 {
+			fmt.Println("usage cf get-analytic-artifacts <Analytic name[@version]|ID> [--dir dir] [--force]")
+			panic(1)
+		}
+		fc := flags.New()
+		fc.NewStringFlagWithDefault("dir", "d", "Directory to save the artifacts to", ".")
+		fc.NewBoolFlag("force", "f", "Overwrite existing files")
+		err := fc.Parse(args[2:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		p.getArtifacts(args[1], fc.String("dir"), fc.Bool("force"))
 	case "add-analytic-artifact":
 		if len(args) < 3 {
-			fmt.Printf("usage cf add-analytic-artifact <Analytic name> <file path> -type <artifact type> -description [artifact description]\n", args[0])
+			fmt.Println("usage cf add-analytic-artifact <Analytic name[@version]|ID> <file path> -type <artifact type> -description [artifact description]")
 			panic(1)
 		}
 		fc := flags.New()
 		fc.NewStringFlag("type", "t", "Artifact type")
-		fc.NewStringFlag("descriptio", "d", "Artifact description")
+		fc.NewStringFlag("description", "d", "Artifact description")
 		err := fc.Parse(args[3:]...)
 		if err != nil {
 			fmt.Println(err)
@@ -125,51 +189,364 @@ func (p *AnalyticsPlugin) Run(cliConnection plugin.CliConnection, args []string)
 			fmt.Println("Specify artifcat type")
 			panic(1)
 		}
+	case "replace-analytic-artifact":
+		if len(args) < 3 {
+			fmt.Println("usage cf replace-analytic-artifact <Analytic name[@version]|ID> <file path> [-type artifact type] [-description artifact description]")
+			panic(1)
+		}
+		fc := flags.New()
+		fc.NewStringFlag("type", "t", "Artifact type, defaults to the type of the replaced artifact")
+		fc.NewStringFlag("description", "d", "Artifact description, defaults to the description of the replaced artifact")
+		err := fc.Parse(args[3:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		p.replaceArtifact(args[1], args[2], fc.String("type"), fc.String("description"))
+	case "sync-analytic-artifacts":
+		if len(args) < 3 {
+			fmt.Println("usage cf sync-analytic-artifacts <Analytic name[@version]|ID> <dir> [--type-pattern pattern=type...] [--delete] [--dry-run] [-f]")
+			panic(1)
+		}
+		fc := flags.New()
+		fc.NewStringSliceFlag("type-pattern", "t", "Artifact type of new files matching a pattern, e.g. *.jar=Executable, flag can be specified multiple times")
+		fc.NewBoolFlag("delete", "", "Delete artifacts that don't exist in the directory")
+		fc.NewBoolFlag("dry-run", "", "Only show the changes")
+		fc.NewBoolFlag("f", "", "Force deletion without confirmation")
+		err := fc.Parse(args[3:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		p.syncArtifacts(args[1], args[2], fc.StringSlice("type-pattern"), fc.Bool("delete"), fc.Bool("dry-run"), fc.Bool("f"))
 	case "delete-analytic-artifact":
 		if len(args) < 3 {
-			fmt.Printf("usage cf delete-analytic-artifact <Analytic name> <file name>\n", args[0])
+			fmt.Println("usage cf delete-analytic-artifact <Analytic name[@version]|ID> <file name> [-f]")
 			panic(1)
 		}
-		p.deleteArtifact(args[1], args[2])
+		fc := flags.NewFlagContext(map[string]flags.FlagSet{
+			"f": &flags.BoolFlag{ShortName: "f", Usage: "Force deletion without confirmation"},
+		})
+		err := fc.Parse(args[3:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		p.deleteArtifact(args[1], args[2], fc.Bool("f"))
 	case "run-analytic":
 		if len(args) < 3 {
-			fmt.Println("usage cf run-analytic <Analytic name> <input file>")
+			fmt.Println("usage cf run-analytic <Analytic name[@version]|ID> <input file>")
 			panic(1)
 		}
 		p.runAnalytic(args[1], args[2])
 	case "validate-analytic":
 		if len(args) < 3 {
-			fmt.Println("usage cf validate-analytic <Analytic name> <input file>")
+			fmt.Println("usage cf validate-analytic <Analytic name[@version]|ID> <input file>")
 			panic(1)
 		}
 		p.validateAnalytic(args[1], args[2])
 	case "delete-analytic":
 		if len(args) < 2 {
-			fmt.Println("usage cf delete-analytic <Analytic name>")
+			fmt.Println("usage cf delete-analytic <Analytic name[@version]|ID> [-f] [--force] [--all-versions]")
 			panic(1)
 		}
-		p.deleteAnalytic(args[1])
+		fc := flags.NewFlagContext(map[string]flags.FlagSet{
+			"f":            &flags.BoolFlag{ShortName: "f", Usage: "Force deletion without confirmation"},
+			"force":        &flags.BoolFlag{Name: "force", Usage: "Delete analytics even if they are deployed"},
+			"all-versions": &flags.BoolFlag{Name: "all-versions", Usage: "Delete all versions of the analytic"},
+		})
+		err := fc.Parse(args[2:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		p.deleteAnalytic(args[1], fc.Bool("f"), fc.Bool("force"), fc.Bool("all-versions"))
 	case "analytic-logs":
 		if len(args) < 2 {
-			fmt.Println("usage cf analytic-logs <Analytic name>")
+			fmt.Println("usage cf analytic-logs <Analytic name[@version]|ID> [--follow] [--since duration] [--tail N] [--stderr-only] [--grep regexp] [--json] [--output FILE]")
 			panic(1)
 		}
-		p.analyticLogs(args[1])
+		fc := flags.New()
+		fc.NewBoolFlag("follow", "f", "Keep printing new log lines until interrupted")
+		fc.NewStringFlag("since", "s", "Only show lines logged within this duration, e.g. 1h")
+		fc.NewIntFlag("tail", "n", "Only show the last N lines")
+		fc.NewBoolFlag("stderr-only", "", "Only show lines written to STDERR")
+		fc.NewStringFlag("grep", "g", "Only show lines whose message matches this regular expression")
+		fc.NewBoolFlag("json", "", "Print log records as JSON lines")
+		fc.NewStringFlag("output", "o", "Write logs to FILE instead of stdout")
+		err := fc.Parse(args[2:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		options := logOptions{
+			follow:     fc.Bool("follow"),
+			tail:       fc.Int("tail"),
+			stderrOnly: fc.Bool("stderr-only"),
+			json:       fc.Bool("json"),
+		}
+		if fc.IsSet("since") {
+			options.since, err = parseAge(fc.String("since"))
+			if err != nil {
+				fmt.Println(err)
+				panic(1)
+			}
+		}
+		if fc.IsSet("grep") {
+			options.grep, err = regexp.Compile(fc.String("grep"))
+			if err != nil {
+				fmt.Printf("Invalid regular expression: %s\n", err)
+				panic(1)
+			}
+		}
+		options.outputPath = fc.String("output")
+		p.analyticLogs(args[1], options)
+	case "analytics-logs-archive":
+		fc := flags.New()
+		fc.NewStringFlagWithDefault("dir", "d", "Directory to write the log files to", ".")
+		fc.NewStringFlag("name", "n", "Only archive analytics whose name matches this regular expression")
+		fc.NewStringSliceFlag("meta", "", "Only archive analytics with this custom metadata key=value, flag can be specified multiple times")
+		fc.NewBoolFlag("gzip", "z", "Compress the log files")
+		err := fc.Parse(args[1:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		var name *regexp.Regexp
+		if fc.IsSet("name") {
+			name, err = regexp.Compile(fc.String("name"))
+			if err != nil {
+				fmt.Printf("Invalid regular expression: %s\n", err)
+				panic(1)
+			}
+		}
+		p.archiveLogs(fc.String("dir"), name, fc.StringSlice("meta"), fc.Bool("gzip"))
 	case "deploy-analytic":
 		if len(args) < 2 {
-			fmt.Println("usage cf deploy-analytic <Analytic name> [-memory mb] [-diskQuota mb] [-instances n]")
+			fmt.Println("usage cf deploy-analytic <Analytic name[@version]|ID> [-memory mb] [-diskQuota mb] [-instances n] [-config deploy.yml] [-env KEY=VAL...]")
 			panic(1)
 		}
 		fc := flags.New()
-		fc.NewIntFlagWithDefault("memory", "m", "Memory size in MB", 512)
-		fc.NewIntFlagWithDefault("diskQuota", "d", "Disk space in MB", 1024)
-		fc.NewIntFlagWithDefault("instances", "i", "Number of instances", 1)
+		fc.NewIntFlag("memory", "m", "Memory size in MB (default 512)")
+		fc.NewIntFlag("diskQuota", "d", "Disk space in MB (default 1024)")
+		fc.NewIntFlag("instances", "i", "Number of instances (default 1)")
+		fc.NewStringFlag("config", "c", "YAML file with the deployment configuration")
+		fc.NewStringSliceFlag("env", "e", "Environment variable KEY=VAL, flag can be specified multiple times")
 		err := fc.Parse(args[2:]...)
 		if err != nil {
 			fmt.Println(err)
 			panic(1)
 		}
 		p.deployAnalytic(args[1], fc)
+	case "release-analytic":
+		if len(args) < 3 {
+			fmt.Println("usage cf release-analytic <Analytic name[@version]|ID> <executable path> --validate-with <input file> [--expect <output>]")
+			panic(1)
+		}
+		fc := flags.New()
+		fc.NewStringFlag("validate-with", "", "Input file to validate the analytic with")
+		fc.NewStringFlag("expect", "", "Expected validation result, or a file containing it")
+		fc.NewIntFlag("memory", "m", "Memory size in MB (default 512)")
+		fc.NewIntFlag("diskQuota", "d", "Disk space in MB (default 1024)")
+		fc.NewIntFlag("instances", "i", "Number of instances (default 1)")
+		fc.NewStringFlag("config", "c", "YAML file with the deployment configuration")
+		fc.NewStringSliceFlag("env", "e", "Environment variable KEY=VAL, flag can be specified multiple times")
+		err := fc.Parse(args[3:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		if !fc.IsSet("validate-with") {
+			fmt.Println("Specify validation input")
+			panic(1)
+		}
+		p.releaseAnalytic(args[1], args[2], fc.String("validate-with"), fc.String("expect"), deploymentConfigFromFlags(fc))
+	case "analytics-plan", "analytics-apply":
+		fc := flags.New()
+		fc.NewStringFlagWithDefault("file", "f", "Path to the analytics manifest", "analytics.yml")
+		err := fc.Parse(args[1:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		if args[0] == "analytics-plan" {
+			p.analyticsPlan(fc.String("file"))
+		} else {
+			p.analyticsApply(fc.String("file"))
+		}
+	case "analytics-export":
+		if len(args) < 2 {
+			fmt.Println("usage cf analytics-export <directory|file.tar.gz>")
+			panic(1)
+		}
+		p.exportCatalog(args[1])
+	case "analytics-import":
+		if len(args) < 2 {
+			fmt.Println("usage cf analytics-import <directory|file.tar.gz> [--existing skip|overwrite] [--force]")
+			panic(1)
+		}
+		fc := flags.New()
+		fc.NewStringFlagWithDefault("existing", "e", "What to do with analytics that already exist (skip|overwrite)", "skip")
+		fc.NewBoolFlag("force", "", "Overwrite deployed analytics as well")
+		err := fc.Parse(args[2:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		p.importCatalog(args[1], fc.String("existing"), fc.Bool("force"))
+	case "promote-analytic":
+		if len(args) < 2 {
+			fmt.Println("usage cf promote-analytic <Analytic name[@version]|ID> --to-space <org/space> [--validate-with <input file>]")
+			panic(1)
+		}
+		fc := flags.New()
+		fc.NewStringFlag("to-space", "s", "Target org and space as <org>/<space>")
+		fc.NewStringFlag("validate-with", "", "Input file to validate the promoted analytic with")
+		err := fc.Parse(args[2:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		if !fc.IsSet("to-space") {
+			fmt.Println("Specify target space")
+			panic(1)
+		}
+		p.promoteAnalytic(args[1], fc.String("to-space"), fc.String("validate-with"))
+	case "analytic-diff":
+		if len(args) < 3 {
+			fmt.Println("usage cf analytic-diff <Analytic name[@version]|ID> <Analytic name[@version]|ID> [--input <input file>]")
+			panic(1)
+		}
+		fc := flags.New()
+		fc.NewStringFlag("input", "i", "Input file to run both analytics with")
+		err := fc.Parse(args[3:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		p.diffAnalytics(args[1], args[2], fc.String("input"))
+	case "clone-analytic":
+		if len(args) < 2 {
+			fmt.Println("usage cf clone-analytic <Analytic name[@version]|ID> --version <new version> [--executable <path to executable>]")
+			panic(1)
+		}
+		fc := flags.New()
+		fc.NewStringFlag("version", "v", "Version of the new analytic")
+		fc.NewStringFlag("executable", "e", "Executable replacing the one of the cloned analytic")
+		err := fc.Parse(args[2:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		if !fc.IsSet("version") {
+			fmt.Println("Specify new analytic version")
+			panic(1)
+		}
+		p.cloneAnalytic(args[1], fc.String("version"), fc.String("executable"))
+	case "analytics-prune":
+		fc := flags.NewFlagContext(map[string]flags.FlagSet{
+			"keep-last":  &flags.IntFlag{Name: "keep-last", Usage: "Number of most recent versions to keep per analytic"},
+			"older-than": &flags.StringFlag{Name: "older-than", Usage: "Only delete versions not updated within this duration, e.g. 30d"},
+			"state":      &flags.StringFlag{Name: "state", Usage: "Only delete versions in this state"},
+			"dry-run":    &flags.BoolFlag{Name: "dry-run", Usage: "Show the versions that would be deleted"},
+			"force":      &flags.BoolFlag{Name: "force", Usage: "Delete deployed versions as well"},
+			"f":          &flags.BoolFlag{ShortName: "f", Usage: "Force deletion without confirmation"},
+		})
+		err := fc.Parse(args[1:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		policy := prunePolicy{
+			keepLast: fc.Int("keep-last"),
+			state:    fc.String("state"),
+			force:    fc.Bool("force"),
+		}
+		if fc.IsSet("older-than") {
+			policy.olderThan, err = parseAge(fc.String("older-than"))
+			if err != nil {
+				fmt.Println(err)
+				panic(1)
+			}
+		}
+		p.pruneAnalytics(policy, fc.Bool("dry-run"), fc.Bool("f"))
+	case "analytic-wait":
+		if len(args) < 2 {
+			fmt.Println("usage cf analytic-wait <Analytic name[@version]|ID> --state <state> [--timeout duration]")
+			panic(1)
+		}
+		fc := flags.New()
+		fc.NewStringFlag("state", "s", "State to wait for, e.g. DEPLOYED")
+		fc.NewStringFlag("timeout", "t", "Give up after this duration, e.g. 10m")
+		err := fc.Parse(args[2:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		if !fc.IsSet("state") {
+			fmt.Println("Specify state to wait for")
+			panic(1)
+		}
+		var timeout time.Duration
+		if fc.IsSet("timeout") {
+			timeout, err = parseAge(fc.String("timeout"))
+			if err != nil {
+				fmt.Println(err)
+				panic(1)
+			}
+		}
+		p.waitForState(args[1], fc.String("state"), timeout)
+	case "scale-analytic":
+		if len(args) < 2 {
+			fmt.Println("usage cf scale-analytic <Analytic name[@version]|ID> [-i instances] [-m memory] [-d diskQuota]")
+			panic(1)
+		}
+		fc := flags.New()
+		fc.NewIntFlag("instances", "i", "Number of instances")
+		fc.NewIntFlag("memory", "m", "Memory size in MB")
+		fc.NewIntFlag("diskQuota", "d", "Disk space in MB")
+		err := fc.Parse(args[2:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		p.scaleAnalytic(args[1], fc.Int("instances"), fc.Int("memory"), fc.Int("diskQuota"))
+	case "analytic-deployments":
+		if len(args) < 2 {
+			fmt.Println("usage cf analytic-deployments <Analytic name[@version]|ID> [--request-id id]")
+			panic(1)
+		}
+		fc := flags.New()
+		fc.NewStringFlag("request-id", "r", "Show details of this deployment request")
+		err := fc.Parse(args[2:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		p.listDeployments(args[1], fc.String("request-id"))
+	case "undeploy-analytic":
+		if len(args) < 2 {
+			fmt.Println("usage cf undeploy-analytic <Analytic name[@version]|ID>")
+			panic(1)
+		}
+		p.undeployAnalytic(args[1])
+	case "analytics-version-pattern":
+		fc := flags.New()
+		fc.NewBoolFlag("unset", "", "Remove the configured version pattern")
+		err := fc.Parse(args[1:]...)
+		if err != nil {
+			fmt.Println(err)
+			panic(1)
+		}
+		pattern := ""
+		if len(fc.Args()) > 0 {
+			pattern = fc.Args()[0]
+		}
+		if pattern != "" && fc.Bool("unset") {
+			fmt.Println("usage cf analytics-version-pattern [regexp | --unset]")
+			panic(1)
+		}
+		p.setVersionPattern(pattern, fc.Bool("unset"))
 	case "analytics-curl":
 		fs := make(map[string]flags.FlagSet)
 		fs["i"] = &flags.BoolFlag{ShortName: "i", Usage: "Include response headers in the output"}
@@ -207,7 +584,15 @@ func (c *AnalyticsPlugin) GetMetadata() plugin.PluginMetadata {
 				HelpText: "List analytics",
 
 				UsageDetails: plugin.Usage{
-					Usage: "analytics\n   cf analytics",
+					Usage: "analytics\n   cf analytics [--wide] [--meta key=value...]",
+				},
+			},
+			{
+				Name:     "analytic",
+				HelpText: "Show analytic details and custom metadata",
+
+				UsageDetails: plugin.Usage{
+					Usage: "analytic\n   cf analytic <Analytic name[@version]|ID> [--no-cache]",
 				},
 			},
 			{
@@ -215,7 +600,7 @@ func (c *AnalyticsPlugin) GetMetadata() plugin.PluginMetadata {
 				HelpText: "Create analytic",
 
 				UsageDetails: plugin.Usage{
-					Usage: "create-analytic\n  cf create-analytic <Analytic name> <path to executable> [-version version] [-author] [-description description] [-taxonomy taxonomy location] [-language (Python|Java|Matlab)] [-metadata custom analytic metadata]",
+					Usage: "create-analytic\n  cf create-analytic <Analytic name> <path to executable> [-version version|-bump major|minor|patch|-git-version] [-version-pattern regexp] [-author] [-description description] [-taxonomy taxonomy location] [-language (Python|Java|Matlab)] [-metadata custom analytic metadata] [--meta key=value...] [--meta-file file.json] [--no-rollback]",
 				},
 			},
 			{
@@ -223,7 +608,7 @@ func (c *AnalyticsPlugin) GetMetadata() plugin.PluginMetadata {
 				HelpText: "Delete analytic",
 
 				UsageDetails: plugin.Usage{
-					Usage: "delete-analytic\n   cf delete-analytic <Analytic name>",
+					Usage: "delete-analytic\n   cf delete-analytic <Analytic name[@version]|ID> [-f] [--force] [--all-versions] [--no-cache]",
 				},
 			},
 			{
@@ -231,7 +616,7 @@ func (c *AnalyticsPlugin) GetMetadata() plugin.PluginMetadata {
 				HelpText: "Validate analytic",
 
 				UsageDetails: plugin.Usage{
-					Usage: "validate-analytic\n   cf validate-analytic <Analytic name> <input file>",
+					Usage: "validate-analytic\n   cf validate-analytic <Analytic name[@version]|ID> <input file> [--no-cache]",
 				},
 			},
 			{
@@ -239,7 +624,47 @@ func (c *AnalyticsPlugin) GetMetadata() plugin.PluginMetadata {
 				HelpText: "deploy analytic",
 
 				UsageDetails: plugin.Usage{
-					Usage: "deploy-analytic\n   cf deploy-analytic <Analytic name>",
+					Usage: "deploy-analytic\n   cf deploy-analytic <Analytic name[@version]|ID> [-memory mb] [-diskQuota mb] [-instances n] [-config deploy.yml] [-env KEY=VAL...] [--no-cache]",
+				},
+			},
+			{
+				Name:     "scale-analytic",
+				HelpText: "Change instances, memory or disk of a deployed analytic",
+
+				UsageDetails: plugin.Usage{
+					Usage: "scale-analytic\n   cf scale-analytic <Analytic name[@version]|ID> [-i instances] [-m memory] [-d diskQuota] [--no-cache]",
+				},
+			},
+			{
+				Name:     "analytic-deployments",
+				HelpText: "List deployment requests of an analytic",
+
+				UsageDetails: plugin.Usage{
+					Usage: "analytic-deployments\n   cf analytic-deployments <Analytic name[@version]|ID> [--request-id id] [--no-cache]",
+				},
+			},
+			{
+				Name:     "undeploy-analytic",
+				HelpText: "Stop a deployed analytic",
+
+				UsageDetails: plugin.Usage{
+					Usage: "undeploy-analytic\n   cf undeploy-analytic <Analytic name[@version]|ID> [--no-cache]",
+				},
+			},
+			{
+				Name:     "analytic-wait",
+				HelpText: "Wait until an analytic reaches the given state",
+
+				UsageDetails: plugin.Usage{
+					Usage: "analytic-wait\n   cf analytic-wait <Analytic name[@version]|ID> --state <state> [--timeout duration] [--no-cache]",
+				},
+			},
+			{
+				Name:     "release-analytic",
+				HelpText: "Upload an executable, validate the analytic and deploy it if validation succeeds",
+
+				UsageDetails: plugin.Usage{
+					Usage: "release-analytic\n   cf release-analytic <Analytic name[@version]|ID> <executable path> --validate-with <input file> [--expect <output>] [-memory mb] [-diskQuota mb] [-instances n] [-config deploy.yml] [-env KEY=VAL...]",
 				},
 			},
 			{
@@ -247,7 +672,7 @@ func (c *AnalyticsPlugin) GetMetadata() plugin.PluginMetadata {
 				HelpText: "Run analytic",
 
 				UsageDetails: plugin.Usage{
-					Usage: "run-analytic\n   cf run-analytic <Analytic name> <input file>",
+					Usage: "run-analytic\n   cf run-analytic <Analytic name[@version]|ID> <input file> [--no-cache]",
 				},
 			},
 			{
@@ -255,7 +680,15 @@ func (c *AnalyticsPlugin) GetMetadata() plugin.PluginMetadata {
 				HelpText: "Get the recent analytic logs",
 
 				UsageDetails: plugin.Usage{
-					Usage: "analytic-logs\n   cf analytic-logs <Analytic name>",
+					Usage: "analytic-logs\n   cf analytic-logs <Analytic name[@version]|ID> [--follow] [--since duration] [--tail N] [--stderr-only] [--grep regexp] [--json] [--output FILE] [--no-cache]",
+				},
+			},
+			{
+				Name:     "analytics-logs-archive",
+				HelpText: "Save the logs of all analytics into timestamped files",
+
+				UsageDetails: plugin.Usage{
+					Usage: "analytics-logs-archive\n   cf analytics-logs-archive [--dir DIR] [--name regexp] [--meta key=value...] [--gzip]",
 				},
 			},
 			{
@@ -263,7 +696,7 @@ func (c *AnalyticsPlugin) GetMetadata() plugin.PluginMetadata {
 				HelpText: "List analytic artifacts",
 
 				UsageDetails: plugin.Usage{
-					Usage: "analytic-artifacts\n   cf analytic-artifacts <Analytic name>",
+					Usage: "analytic-artifacts\n   cf analytic-artifacts <Analytic name[@version]|ID> [--no-cache]",
 				},
 			},
 			{
@@ -271,7 +704,15 @@ func (c *AnalyticsPlugin) GetMetadata() plugin.PluginMetadata {
 				HelpText: "Get analytic artifact",
 
 				UsageDetails: plugin.Usage{
-					Usage: "get-analytic-artifacts\n   cf get-analytic-artifact <Analytic name> <file name>",
+					Usage: "get-analytic-artifact\n   cf get-analytic-artifact <Analytic name[@version]|ID> <file name> [-o path | --dir dir] [--force] [--stdout] [--no-cache]",
+				},
+			},
+			{
+				Name:     "get-analytic-artifacts",
+				HelpText: "Download all artifacts of an analytic",
+
+				UsageDetails: plugin.Usage{
+					Usage: "get-analytic-artifacts\n   cf get-analytic-artifacts <Analytic name[@version]|ID> [--dir dir] [--force] [--no-cache]",
 				},
 			},
 			{
@@ -279,7 +720,23 @@ func (c *AnalyticsPlugin) GetMetadata() plugin.PluginMetadata {
 				HelpText: "Add analytic artifact",
 
 				UsageDetails: plugin.Usage{
-					Usage: "add-analytic-artifacts\n   cf add-analytic-artifact <Analytic name> <file name> -type <artifact type> -description [artifact description]",
+					Usage: "add-analytic-artifacts\n   cf add-analytic-artifact <Analytic name[@version]|ID> <file name> -type <artifact type> -description [artifact description] [--no-cache]",
+				},
+			},
+			{
+				Name:     "replace-analytic-artifact",
+				HelpText: "Replace analytic artifact with a new version of the file",
+
+				UsageDetails: plugin.Usage{
+					Usage: "replace-analytic-artifact\n   cf replace-analytic-artifact <Analytic name[@version]|ID> <file path> [-type artifact type] [-description artifact description] [--no-cache]",
+				},
+			},
+			{
+				Name:     "sync-analytic-artifacts",
+				HelpText: "Upload new and changed files of a directory as analytic artifacts",
+
+				UsageDetails: plugin.Usage{
+					Usage: "sync-analytic-artifacts\n   cf sync-analytic-artifacts <Analytic name[@version]|ID> <dir> [--type-pattern pattern=type...] [--delete] [--dry-run] [-f] [--no-cache]",
 				},
 			},
 			{
@@ -287,7 +744,7 @@ func (c *AnalyticsPlugin) GetMetadata() plugin.PluginMetadata {
 				HelpText: "Delete analytic artifact",
 
 				UsageDetails: plugin.Usage{
-					Usage: "delete-analytic-artifacts\n   cf delete-analytic-artifact <Analytic name> <file name>",
+					Usage: "delete-analytic-artifacts\n   cf delete-analytic-artifact <Analytic name[@version]|ID> <file name> [-f] [--no-cache]",
 				},
 			},
 			{
@@ -306,6 +763,78 @@ func (c *AnalyticsPlugin) GetMetadata() plugin.PluginMetadata {
 					Usage: "add-taxonomy\n   cf add-taxonomy <Taxonomy>",
 				},
 			},
+			{
+				Name:     "analytics-plan",
+				HelpText: "Show the changes needed to bring the catalog in line with the analytics manifest",
+
+				UsageDetails: plugin.Usage{
+					Usage: "analytics-plan\n   cf analytics-plan [-f analytics.yml]",
+				},
+			},
+			{
+				Name:     "analytics-apply",
+				HelpText: "Create, update, upload and deploy analytics described in the analytics manifest",
+
+				UsageDetails: plugin.Usage{
+					Usage: "analytics-apply\n   cf analytics-apply [-f analytics.yml]",
+				},
+			},
+			{
+				Name:     "analytics-export",
+				HelpText: "Export all analytics, their artifacts and the taxonomy",
+
+				UsageDetails: plugin.Usage{
+					Usage: "analytics-export\n   cf analytics-export <directory|file.tar.gz>",
+				},
+			},
+			{
+				Name:     "analytics-import",
+				HelpText: "Import analytics exported by analytics-export into the targeted catalog",
+
+				UsageDetails: plugin.Usage{
+					Usage: "analytics-import\n   cf analytics-import <directory|file.tar.gz> [--existing skip|overwrite] [--force]",
+				},
+			},
+			{
+				Name:     "promote-analytic",
+				HelpText: "Copy an analytic with its artifacts into the catalog of another space",
+
+				UsageDetails: plugin.Usage{
+					Usage: "promote-analytic\n   cf promote-analytic <Analytic name[@version]|ID> --to-space <org/space> [--validate-with <input file>]",
+				},
+			},
+			{
+				Name:     "analytic-diff",
+				HelpText: "Compare two analytics, their artifacts and optionally their outputs",
+
+				UsageDetails: plugin.Usage{
+					Usage: "analytic-diff\n   cf analytic-diff <Analytic name[@version]|ID> <Analytic name[@version]|ID> [--input <input file>]",
+				},
+			},
+			{
+				Name:     "clone-analytic",
+				HelpText: "Create a new version of an analytic with the same metadata and artifacts",
+
+				UsageDetails: plugin.Usage{
+					Usage: "clone-analytic\n   cf clone-analytic <Analytic name[@version]|ID> --version <new version> [--executable <path to executable>]",
+				},
+			},
+			{
+				Name:     "analytics-prune",
+				HelpText: "Delete old analytic versions",
+
+				UsageDetails: plugin.Usage{
+					Usage: "analytics-prune\n   cf analytics-prune [--keep-last N] [--older-than 30d] [--state STATE] [--dry-run] [--force] [-f]",
+				},
+			},
+			{
+				Name:     "analytics-version-pattern",
+				HelpText: "Show or set the regular expression versions of new analytics have to match",
+
+				UsageDetails: plugin.Usage{
+					Usage: "analytics-version-pattern\n   cf analytics-version-pattern [regexp | --unset]",
+				},
+			},
 			{
 				Name:     "analytics-curl",
 				HelpText: "Executes a request to the targeted Analytics Catalog API endpoint",
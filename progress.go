@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	progressInterval = 200 * time.Millisecond
+	progressBarWidth = 30
+)
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n) / unit
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f TB", value)
+}
+
+// progressBar draws a bar such as [=========>          ]  45%.
+func progressBar(done, total int64) string {
+	percent := int64(100)
+	if total > 0 && done < total {
+		percent = done * 100 / total
+	}
+	filled := int(percent * progressBarWidth / 100)
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+	return fmt.Sprintf("[%s] %3d%%", bar, percent)
+}
+
+// progressReader prints how much of the wrapped reader was consumed and at
+// which rate, with a progress bar if the total is known. A negative total
+// means the size is unknown and only the byte counter is shown.
+type progressReader struct {
+	reader   io.Reader
+	label    string
+	total    int64
+	read     int64
+	started  time.Time
+	printed  time.Time
+	finished bool
+}
+
+func newProgressReader(reader io.Reader, label string, total int64) *progressReader {
+	return &progressReader{reader: reader, label: label, total: total, started: time.Now()}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if err != nil {
+		r.finish()
+	} else if time.Since(r.printed) >= progressInterval {
+		r.print()
+	}
+	return n, err
+}
+
+// finish prints the final progress and ends the line, so that messages
+// printed after a failed transfer start on a new line.
+func (r *progressReader) finish() {
+	if r.finished {
+		return
+	}
+	r.finished = true
+	r.print()
+	fmt.Fprintln(os.Stdout)
+}
+
+func (r *progressReader) print() {
+	r.printed = time.Now()
+	elapsed := r.printed.Sub(r.started).Seconds()
+	rate := int64(0)
+	if elapsed > 0 {
+		rate = int64(float64(r.read) / elapsed)
+	}
+	if r.total >= 0 {
+		fmt.Fprintf(os.Stdout, "\r%s %s %s / %s (%s/s)   ", r.label, progressBar(r.read, r.total), formatBytes(r.read), formatBytes(r.total), formatBytes(rate))
+	} else {
+		fmt.Fprintf(os.Stdout, "\r%s %s (%s/s)   ", r.label, formatBytes(r.read), formatBytes(rate))
+	}
+}
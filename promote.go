@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// targetSpace switches the cf CLI to org/space and points the plugin at the
+// analytics catalog bound there.
+func (p *AnalyticsPlugin) targetSpace(org, space string) error {
+	_, err := p.cliConnection.CliCommandWithoutTerminalOutput("target", "-o", org, "-s", space)
+	if err != nil {
+		return err
+	}
+	p.UaaGuid = ""
+	p.AnalyticsGuid = ""
+	p.AuthToken = ""
+	p.checkForAnalyticsService()
+	p.createClient()
+	return nil
+}
+
+// promoteAnalytic copies an analytic with all of its artifacts into the
+// catalog of another space. The source entry ID is recorded in the custom
+// metadata of the copy under "promotedFrom".
+func (p *AnalyticsPlugin) promoteAnalytic(analyticName, toSpace, validateInput string) {
+	target := strings.SplitN(toSpace, "/", 2)
+	if len(target) != 2 || target[0] == "" || target[1] == "" {
+		fmt.Println("Target space must be specified as <org>/<space>")
+		panic(1)
+	}
+	currentOrg, err := p.cliConnection.GetCurrentOrg()
+	if err != nil {
+		fmt.Printf("Failed to get current org: %s\n", err)
+		panic(1)
+	}
+	currentSpace, err := p.cliConnection.GetCurrentSpace()
+	if err != nil {
+		fmt.Printf("Failed to get current space: %s\n", err)
+		panic(1)
+	}
+
+	entry := p.analyticEntry(analyticName)
+	dir, err := ioutil.TempDir("", "analytics-promote")
+	if err != nil {
+		fmt.Printf("Failed to promote analytic: %s\n", err)
+		panic(1)
+	}
+	defer os.RemoveAll(dir)
+
+	p.ui.Say("Exporting analytic %s %s from %s/%s...", entry.Name, entry.Version, currentOrg.Name, currentSpace.Name)
+	entry.CustomMetadata = setMetadataValue(entry.CustomMetadata, "promotedFrom", entry.Id)
+	if err = p.exportAnalytic(entry, dir); err != nil {
+		fmt.Printf("Failed to export analytic: %s\n", err)
+		panic(1)
+	}
+	p.ui.Ok()
+
+	source := *p
+	defer func() {
+		p.cliConnection.CliCommandWithoutTerminalOutput("target", "-o", currentOrg.Name, "-s", currentSpace.Name)
+		p.UaaGuid = source.UaaGuid
+		p.AnalyticsGuid = source.AnalyticsGuid
+		p.AuthToken = source.AuthToken
+		p.client = source.client
+		p.saveConfig()
+	}()
+	p.ui.Say("Targeting %s...", toSpace)
+	if err = p.targetSpace(target[0], target[1]); err != nil {
+		fmt.Printf("Failed to target %s: %s\n", toSpace, err)
+		panic(1)
+	}
+	p.ui.Ok()
+
+	for _, existing := range p.analyticsList() {
+		if existing.Name == entry.Name && existing.Version == entry.Version {
+			fmt.Printf("Analytic %s %s already exists in %s\n", entry.Name, entry.Version, toSpace)
+			panic(1)
+		}
+	}
+	if entry.TaxonomyLocation != "" {
+		if err = p.addTaxonomyTree(taxonomyFromPath(entry.TaxonomyLocation)); err != nil {
+			fmt.Printf("Failed to add taxonomy %s: %s\n", entry.TaxonomyLocation, err)
+			panic(1)
+		}
+	}
+	p.ui.Say("Importing analytic %s %s into %s...", entry.Name, entry.Version, toSpace)
+	analyticId, err := p.importAnalytic(dir)
+	if err != nil {
+		fmt.Printf("Failed to import analytic: %s\n", err)
+		if analyticId != "" {
+			if re := p.deleteAnalyticById(analyticId); re != nil {
+				fmt.Printf("Failed to remove partially imported analytic %s (ID %s) from %s: %s\n", entry.Name, analyticId, toSpace, re)
+			}
+		}
+		panic(1)
+	}
+	p.ui.Ok()
+
+	if validateInput != "" {
+		p.ui.Say("Validating analytic with %s...", filepath.Base(validateInput))
+		result, err := p.validateAnalyticById(analyticId, validateInput)
+		if err != nil {
+			fmt.Printf("Failed to validate analytic: %s\n", err)
+			panic(1)
+		}
+		if result.Status != "COMPLETED" {
+			fmt.Printf("Validation failed: %s\n", result.Message)
+			panic(1)
+		}
+		p.ui.Say(result.Message)
+	}
+}
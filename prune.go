@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var catalogTimeFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000-0700",
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// parseCatalogTime parses catalog timestamps, returning the zero time if the
+// format isn't recognized.
+func parseCatalogTime(s string) time.Time {
+	for _, format := range catalogTimeFormats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseAge parses durations such as 90m, 12h or 30d.
+func parseAge(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %s", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func entryTime(entry AnalyticCatalogEntry) time.Time {
+	if t := parseCatalogTime(entry.UpdatedTimestamp); !t.IsZero() {
+		return t
+	}
+	return parseCatalogTime(entry.CreatedTimestamp)
+}
+
+type prunePolicy struct {
+	keepLast  int
+	olderThan time.Duration
+	state     string
+	force     bool
+}
+
+// pruneCandidates returns the entries matching all given policies. Deployed
+// analytics are kept unless forced. With keepLast, analytics with a version
+// whose timestamps can't be parsed can't be ordered, so none of their versions
+// are pruned and their names are returned as unordered.
+func pruneCandidates(entries []AnalyticCatalogEntry, policy prunePolicy) (candidates []AnalyticCatalogEntry, unordered []string) {
+	byName := make(map[string][]AnalyticCatalogEntry)
+	var names []string
+	for _, entry := range entries {
+		if _, ok := byName[entry.Name]; !ok {
+			names = append(names, entry.Name)
+		}
+		byName[entry.Name] = append(byName[entry.Name], entry)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	for _, name := range names {
+		versions := byName[name]
+		if policy.keepLast > 0 && hasUnknownTime(versions) {
+			unordered = append(unordered, name)
+			continue
+		}
+		sort.SliceStable(versions, func(i, j int) bool {
+			return entryTime(versions[i]).After(entryTime(versions[j]))
+		})
+		for i, entry := range versions {
+			if policy.keepLast > 0 && i < policy.keepLast {
+				continue
+			}
+			if policy.olderThan > 0 {
+				t := entryTime(entry)
+				if t.IsZero() || now.Sub(t) < policy.olderThan {
+					continue
+				}
+			}
+			if policy.state != "" && !strings.EqualFold(entry.State, policy.state) {
+				continue
+			}
+			if entry.State == "DEPLOYED" && !policy.force {
+				continue
+			}
+			candidates = append(candidates, entry)
+		}
+	}
+	return candidates, unordered
+}
+
+func hasUnknownTime(entries []AnalyticCatalogEntry) bool {
+	for _, entry := range entries {
+		if entryTime(entry).IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *AnalyticsPlugin) pruneAnalytics(policy prunePolicy, dryRun, skipConfirm bool) {
+	if policy.keepLast <= 0 && policy.olderThan <= 0 && policy.state == "" {
+		fmt.Println("Specify at least one of --keep-last, --older-than or --state")
+		panic(1)
+	}
+	p.ui.Say("Getting analytics list...")
+	candidates, unordered := pruneCandidates(p.analyticsList(), policy)
+	p.ui.Ok()
+	for _, name := range unordered {
+		p.ui.Say("Skipping analytic %s, the timestamps of its versions can't be read to find the last %d", name, policy.keepLast)
+	}
+	if len(candidates) == 0 {
+		p.ui.Say("Nothing to prune")
+		return
+	}
+
+	table := p.ui.Table([]string{"Name", "Version", "ID", "State", "Updated"})
+	for _, entry := range candidates {
+		table.Add(entry.Name, entry.Version, entry.Id, entry.State, entry.UpdatedTimestamp)
+	}
+	table.Print()
+	if dryRun {
+		return
+	}
+	if !skipConfirm && !p.ui.Confirm(fmt.Sprintf("Really delete %d analytic(s) with all of their artifacts?", len(candidates))) {
+		return
+	}
+	for _, entry := range candidates {
+		p.ui.Say("Deleting analytic %s@%s...", entry.Name, entry.Version)
+		if e := p.deleteAnalyticById(entry.Id); e != nil {
+			fmt.Printf("Failed to delete analytic: %s\n", e)
+			panic(1)
+		}
+		p.ui.Ok()
+	}
+}
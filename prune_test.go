@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPruneCandidates(t *testing.T) {
+	recent := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	entry := func(name, version, state, updated string) AnalyticCatalogEntry {
+		return AnalyticCatalogEntry{Id: name + "@" + version, Name: name, Version: version, State: state, UpdatedTimestamp: updated}
+	}
+	entries := []AnalyticCatalogEntry{
+		entry("a", "1.0.0", "", "2001-01-01T00:00:00Z"),
+		entry("a", "1.0.2", "DEPLOYED", recent),
+		entry("a", "1.0.1", "", "2002-01-01T00:00:00Z"),
+		entry("b", "1.0.0", "FAILED", "2001-01-01T00:00:00Z"),
+		entry("b", "1.0.1", "", "not a timestamp"),
+		entry("c", "1.0.0", "DEPLOYED", "2001-01-01T00:00:00Z"),
+	}
+
+	tests := []struct {
+		name      string
+		policy    prunePolicy
+		ids       []string
+		unordered []string
+	}{
+		{"keep last", prunePolicy{keepLast: 1}, []string{"a@1.0.1", "a@1.0.0"}, []string{"b"}},
+		{"keep last two", prunePolicy{keepLast: 2}, []string{"a@1.0.0"}, []string{"b"}},
+		{"older than", prunePolicy{olderThan: 24 * time.Hour}, []string{"a@1.0.1", "a@1.0.0", "b@1.0.0"}, nil},
+		{"older than forced", prunePolicy{olderThan: 24 * time.Hour, force: true}, []string{"a@1.0.1", "a@1.0.0", "b@1.0.0", "c@1.0.0"}, nil},
+		{"state", prunePolicy{state: "failed"}, []string{"b@1.0.0"}, nil},
+		{"state deployed", prunePolicy{state: "DEPLOYED"}, nil, nil},
+		{"keep last and state", prunePolicy{keepLast: 1, state: "DEPLOYED", force: true}, nil, []string{"b"}},
+	}
+	for _, test := range tests {
+		candidates, unordered := pruneCandidates(entries, test.policy)
+		var ids []string
+		for _, candidate := range candidates {
+			ids = append(ids, candidate.Id)
+		}
+		if !reflect.DeepEqual(ids, test.ids) {
+			t.Errorf("%s: got candidates %v, want %v", test.name, ids, test.ids)
+		}
+		if !reflect.DeepEqual(unordered, test.unordered) {
+			t.Errorf("%s: got unordered %v, want %v", test.name, unordered, test.unordered)
+		}
+	}
+}
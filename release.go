@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// findAnalytic looks up an analytic by ID or name[@version] without failing
+// when no analytic has the name. Ambiguous names and unknown IDs still fail.
+func (p *AnalyticsPlugin) findAnalytic(analyticName string) (AnalyticCatalogEntry, bool) {
+	analyticId, ok := p.findAnalyticId(analyticName)
+	if !ok {
+		return AnalyticCatalogEntry{}, false
+	}
+	return p.analyticEntry(analyticId), true
+}
+
+// sameResult compares validation results, as JSON if both are JSON.
+func sameResult(actual, expected string) bool {
+	var a, e interface{}
+	if json.Unmarshal([]byte(actual), &a) == nil && json.Unmarshal([]byte(expected), &e) == nil {
+		return reflect.DeepEqual(a, e)
+	}
+	return strings.TrimSpace(actual) == strings.TrimSpace(expected)
+}
+
+// replaceExecutable uploads a new executable and removes the previous ones.
+func (p *AnalyticsPlugin) replaceExecutable(analyticId, executablePath string) error {
+	previous := p.artifactsList(analyticId)
+	if err := p.uploadArtifact(analyticId, executablePath, "Executable", ""); err != nil {
+		return err
+	}
+	for _, artifact := range previous {
+		if artifact.Type != "Executable" {
+			continue
+		}
+		if err := p.deleteArtifactById(analyticId, artifact.Id); err != nil {
+			return fmt.Errorf("failed to remove previous executable %s: %s", artifact.Filename, err)
+		}
+	}
+	return nil
+}
+
+// releaseAnalytic uploads the executable, creating the analytic if needed,
+// validates it with the given input and deploys it only if the validation
+// completed with the expected result.
+func (p *AnalyticsPlugin) releaseAnalytic(analyticName, executablePath, inputFilePath, expect string, config AnalyticDeploymentConfiguration) {
+	abort := func(step string, err interface{}) {
+		fmt.Printf("Release of analytic %s aborted at %s: %s\n", analyticName, step, err)
+		panic(1)
+	}
+	if _, err := os.Stat(executablePath); err != nil {
+		abort("upload", err)
+	}
+	if expect != "" {
+		if data, err := ioutil.ReadFile(expect); err == nil {
+			expect = string(data)
+		}
+	}
+
+	var analyticId string
+	if analytic, ok := p.findAnalytic(analyticName); ok {
+		analyticId = analytic.Id
+		p.ui.Say("Replacing executable of analytic %s@%s...", analytic.Name, analytic.Version)
+		if err := p.replaceExecutable(analyticId, executablePath); err != nil {
+			abort("upload", err)
+		}
+	} else {
+		name, version := splitAnalyticRef(analyticName)
+		bump := ""
+		if version == "" {
+			bump = "patch"
+		}
+		version = p.analyticVersion(name, version, bump, false, p.VersionPattern)
+		author, _ := p.cliConnection.Username()
+		p.ui.Say("Creating analytic %s@%s...", name, version)
+		analyticId = p.createAnalytic(name, executablePath, version, author, executableLanguage(executablePath), "", "", "", true)
+	}
+	p.ui.Ok()
+
+	p.ui.Say("Validating analytic with %s...", inputFilePath)
+	result, err := p.validateAnalyticById(analyticId, inputFilePath)
+	if err != nil {
+		abort("validation", err)
+	}
+	if result.Status != "COMPLETED" {
+		abort("validation", fmt.Sprintf("status %s: %s", result.Status, result.Message))
+	}
+	if expect != "" && !sameResult(result.Result, expect) {
+		abort("validation", fmt.Sprintf("unexpected result\n%s", result.Result))
+	}
+	p.ui.Ok()
+
+	p.printDeploymentConfig(config)
+	p.ui.Say("Deploying analytic %s...", analyticName)
+	deployment, err := p.deployAnalyticById(analyticId, config)
+	if err != nil {
+		abort("deployment", err)
+	}
+	if deployment.Status != "COMPLETED" {
+		abort("deployment", fmt.Sprintf("status %s: %s", deployment.Status, deployment.Message))
+	}
+	p.ui.Ok()
+	p.ui.Say("Analytic %s released", analyticName)
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A catalog snapshot is a directory, optionally packed as a tar.gz, laid out as
+//
+//	taxonomy.json
+//	analytics/<id>/entry.json
+//	analytics/<id>/artifacts.json
+//	analytics/<id>/artifacts/<artifact id>/<filename>
+
+func isTarGz(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func readJSONFile(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// snapshotArtifact is an artifact in artifacts.json, Path is the file
+// relative to the analytic directory.
+type snapshotArtifact struct {
+	Artifact
+	Path string `json:"path"`
+}
+
+// exportAnalytic writes the catalog entry and all of its artifacts into dir.
+// Artifacts are stored under their IDs so that file names can repeat.
+func (p *AnalyticsPlugin) exportAnalytic(entry AnalyticCatalogEntry, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := writeJSONFile(filepath.Join(dir, "entry.json"), entry); err != nil {
+		return err
+	}
+	var artifacts []snapshotArtifact
+	for _, artifact := range p.artifactsList(entry.Id) {
+		path := filepath.Join("artifacts", artifact.Id, filepath.Base(artifact.Filename))
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0755); err != nil {
+			return err
+		}
+		if _, err := p.saveArtifact(artifact.Id, filepath.Join(dir, path)); err != nil {
+			return fmt.Errorf("%s: %s", artifact.Filename, err)
+		}
+		artifacts = append(artifacts, snapshotArtifact{artifact, filepath.ToSlash(path)})
+	}
+	return writeJSONFile(filepath.Join(dir, "artifacts.json"), artifacts)
+}
+
+// importAnalytic recreates an analytic exported by exportAnalytic in the
+// targeted catalog and returns its new ID.
+func (p *AnalyticsPlugin) importAnalytic(dir string) (string, error) {
+	var entry AnalyticCatalogEntry
+	if err := readJSONFile(filepath.Join(dir, "entry.json"), &entry); err != nil {
+		return "", err
+	}
+	var artifacts []snapshotArtifact
+	if err := readJSONFile(filepath.Join(dir, "artifacts.json"), &artifacts); err != nil {
+		return "", err
+	}
+	entry.Id = ""
+	entry.State = ""
+	entry.CreatedTimestamp = ""
+	entry.UpdatedTimestamp = ""
+	created, err := p.createCatalogEntry(entry)
+	if err != nil {
+		return "", err
+	}
+	for _, artifact := range artifacts {
+		if artifact.Path == "" {
+			artifact.Path = filepath.Join("artifacts", filepath.Base(artifact.Filename))
+		}
+		path := filepath.Join(dir, filepath.FromSlash(artifact.Path))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return created.Id, fmt.Errorf("invalid artifact path %s", artifact.Path)
+		}
+		if err := p.uploadArtifact(created.Id, path, artifact.Type, artifact.Description); err != nil {
+			return created.Id, fmt.Errorf("%s: %s", artifact.Filename, err)
+		}
+	}
+	return created.Id, nil
+}
+
+func (p *AnalyticsPlugin) exportCatalog(target string) {
+	dir := target
+	if isTarGz(target) {
+		tmp, err := ioutil.TempDir("", "analytics-export")
+		if err != nil {
+			fmt.Printf("Failed to export catalog: %s\n", err)
+			panic(1)
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Printf("Failed to export catalog: %s\n", err)
+		panic(1)
+	}
+
+	p.ui.Say("Exporting taxonomy...")
+	if err := writeJSONFile(filepath.Join(dir, "taxonomy.json"), p.taxonomyList()); err != nil {
+		fmt.Printf("Failed to export taxonomy: %s\n", err)
+		panic(1)
+	}
+	p.ui.Ok()
+
+	for _, entry := range p.analyticsList() {
+		p.ui.Say("Exporting analytic %s %s...", entry.Name, entry.Version)
+		if err := p.exportAnalytic(entry, filepath.Join(dir, "analytics", entry.Id)); err != nil {
+			fmt.Printf("Failed to export analytic %s: %s\n", entry.Name, err)
+			panic(1)
+		}
+		p.ui.Ok()
+	}
+
+	if isTarGz(target) {
+		if err := writeTarGz(dir, target); err != nil {
+			fmt.Printf("Failed to export catalog: %s\n", err)
+			panic(1)
+		}
+	}
+	p.ui.Say("Catalog exported to %s", target)
+}
+
+// importCatalog recreates a snapshot in the targeted catalog. Analytics that
+// already exist with the same name and version are skipped, or replaced when
+// policy is "overwrite": the existing entry is only deleted once the import
+// succeeded, and deployed entries are only replaced when forced.
+func (p *AnalyticsPlugin) importCatalog(source, policy string, force bool) {
+	if policy != "skip" && policy != "overwrite" {
+		fmt.Printf("Unknown import policy %s, use skip or overwrite\n", policy)
+		panic(1)
+	}
+	dir := source
+	if isTarGz(source) {
+		tmp, err := ioutil.TempDir("", "analytics-import")
+		if err != nil {
+			fmt.Printf("Failed to import catalog: %s\n", err)
+			panic(1)
+		}
+		defer os.RemoveAll(tmp)
+		if err = extractTarGz(source, tmp); err != nil {
+			fmt.Printf("Failed to import catalog: %s\n", err)
+			panic(1)
+		}
+		dir = tmp
+	}
+
+	analyticDirs, err := ioutil.ReadDir(filepath.Join(dir, "analytics"))
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Printf("Failed to import catalog: %s\n", err)
+		panic(1)
+	}
+	existing := p.analyticsList()
+	entries := make([]AnalyticCatalogEntry, len(analyticDirs))
+	var deployed []string
+	for i, analyticDir := range analyticDirs {
+		path := filepath.Join(dir, "analytics", analyticDir.Name())
+		if err := readJSONFile(filepath.Join(path, "entry.json"), &entries[i]); err != nil {
+			fmt.Printf("Failed to import %s: %s\n", path, err)
+			panic(1)
+		}
+		for _, e := range existing {
+			if e.Name == entries[i].Name && e.Version == entries[i].Version && e.State == "DEPLOYED" {
+				deployed = append(deployed, fmt.Sprintf("%s@%s", e.Name, e.Version))
+			}
+		}
+	}
+	if policy == "overwrite" && len(deployed) > 0 && !force {
+		fmt.Printf("Refusing to overwrite deployed analytics %s, use --force to overwrite them anyway\n", strings.Join(deployed, ", "))
+		panic(1)
+	}
+
+	var taxonomy []Taxonomy
+	if err := readJSONFile(filepath.Join(dir, "taxonomy.json"), &taxonomy); err != nil {
+		fmt.Printf("Failed to import taxonomy: %s\n", err)
+		panic(1)
+	}
+	p.ui.Say("Importing taxonomy...")
+	for _, t := range taxonomy {
+		if err := p.addTaxonomyTree(t); err != nil {
+			fmt.Printf("Failed to import taxonomy: %s\n", err)
+			panic(1)
+		}
+	}
+	p.ui.Ok()
+
+	for i, analyticDir := range analyticDirs {
+		entry := entries[i]
+		var replaced []AnalyticCatalogEntry
+		for _, e := range existing {
+			if e.Name == entry.Name && e.Version == entry.Version {
+				replaced = append(replaced, e)
+			}
+		}
+		if len(replaced) > 0 && policy == "skip" {
+			p.ui.Say("Analytic %s %s already exists, skipping", entry.Name, entry.Version)
+			continue
+		}
+		p.ui.Say("Importing analytic %s %s...", entry.Name, entry.Version)
+		id, err := p.importAnalytic(filepath.Join(dir, "analytics", analyticDir.Name()))
+		if err != nil {
+			fmt.Printf("Failed to import analytic %s: %s\n", entry.Name, err)
+			if id != "" {
+				if re := p.deleteAnalyticById(id); re != nil {
+					fmt.Printf("Failed to remove partially imported analytic %s (ID %s): %s\n", entry.Name, id, re)
+				}
+			}
+			panic(1)
+		}
+		p.ui.Ok()
+		for _, e := range replaced {
+			p.ui.Say("Removing previous analytic %s %s (ID %s)...", e.Name, e.Version, e.Id)
+			if err := p.deleteAnalyticById(e.Id); err != nil {
+				fmt.Printf("Failed to delete analytic %s: %s\n", e.Name, err)
+				panic(1)
+			}
+			p.ui.Ok()
+		}
+	}
+}
+
+func writeTarGz(dir, target string) error {
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	// Closing flushes the archive, a failure means it is truncated.
+	for _, c := range []io.Closer{tw, gz, file} {
+		if e := c.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func extractTarGz(source, dir string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0755)
+		case tar.TypeReg:
+			err = extractTarFile(tr, path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractTarFile(r io.Reader, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return err
+}
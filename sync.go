@@ -0,0 +1,206 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultTypePatterns map local files to artifact types when syncing, after
+// the patterns given on the command line.
+var defaultTypePatterns = []string{
+	"*.jar=Executable",
+	"*.zip=Executable",
+	"*.md=Documentation",
+	"*.pdf=Documentation",
+	"*.txt=Documentation",
+}
+
+type artifactTypePattern struct {
+	pattern      string
+	artifactType string
+}
+
+type syncAction struct {
+	Filename string
+	Type     string
+	Action   string
+	run      func() error
+}
+
+func parseTypePatterns(patterns []string) ([]artifactTypePattern, error) {
+	var parsed []artifactTypePattern
+	for _, pattern := range append(patterns, defaultTypePatterns...) {
+		kv := strings.SplitN(pattern, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid type pattern %s, use pattern=type", pattern)
+		}
+		if _, err := filepath.Match(kv[0], ""); err != nil {
+			return nil, fmt.Errorf("invalid type pattern %s: %s", pattern, err)
+		}
+		parsed = append(parsed, artifactTypePattern{kv[0], kv[1]})
+	}
+	return parsed, nil
+}
+
+func artifactTypeOf(filename string, patterns []artifactTypePattern) string {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p.pattern, filename); ok {
+			return p.artifactType
+		}
+	}
+	return ""
+}
+
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// planSync compares the regular files in dir, hidden ones excluded, with the
+// analytic artifacts by name and sha256 checksum. New files get their type
+// from the patterns, changed ones keep the type of the remote artifact.
+func (p *AnalyticsPlugin) planSync(analyticId, dir string, patterns []artifactTypePattern, deleteExtra bool) ([]syncAction, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	local := make(map[string]string)
+	for _, file := range files {
+		if file.Mode().IsRegular() && !strings.HasPrefix(file.Name(), ".") {
+			local[file.Name()] = filepath.Join(dir, file.Name())
+		}
+	}
+	remote := make(map[string]Artifact)
+	var compared []Artifact
+	for _, artifact := range p.artifactsList(analyticId) {
+		remote[artifact.Filename] = artifact
+		if _, ok := local[artifact.Filename]; ok {
+			compared = append(compared, artifact)
+		}
+	}
+	checksums := p.artifactChecksums(compared)
+
+	var filenames []string
+	for filename := range local {
+		filenames = append(filenames, filename)
+	}
+	for filename := range remote {
+		if _, ok := local[filename]; !ok {
+			filenames = append(filenames, filename)
+		}
+	}
+	sort.Strings(filenames)
+
+	var actions []syncAction
+	for _, filename := range filenames {
+		filename := filename
+		path, isLocal := local[filename]
+		artifact, isRemote := remote[filename]
+		switch {
+		case !isLocal:
+			if !deleteExtra {
+				actions = append(actions, syncAction{filename, artifact.Type, "remote only", nil})
+				continue
+			}
+			actions = append(actions, syncAction{filename, artifact.Type, "delete", func() error {
+				for _, a := range p.artifactsList(analyticId) {
+					if a.Filename == filename {
+						if err := p.deleteArtifactById(analyticId, a.Id); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			}})
+		case !isRemote:
+			artifactType := artifactTypeOf(filename, patterns)
+			if artifactType == "" {
+				actions = append(actions, syncAction{filename, "", "skipped (no type pattern)", nil})
+				continue
+			}
+			actions = append(actions, syncAction{filename, artifactType, "upload", func() error {
+				return p.uploadArtifact(analyticId, path, artifactType, "")
+			}})
+		default:
+			sum, err := fileChecksum(path)
+			if err != nil {
+				return nil, err
+			}
+			if sum == checksums[filename] {
+				actions = append(actions, syncAction{filename, artifact.Type, "unchanged", nil})
+				continue
+			}
+			actions = append(actions, syncAction{filename, artifact.Type, "replace", func() error {
+				return p.replaceArtifactById(analyticId, path, "", "")
+			}})
+		}
+	}
+	return actions, nil
+}
+
+func (p *AnalyticsPlugin) syncArtifacts(analyticName, dir string, typePatterns []string, deleteExtra, dryRun, skipConfirm bool) {
+	patterns, err := parseTypePatterns(typePatterns)
+	if err != nil {
+		fmt.Println(err)
+		panic(1)
+	}
+	analyticId := p.analyticId(analyticName)
+	p.ui.Say("Comparing %s with the artifacts of analytic %s...", dir, analyticName)
+	actions, err := p.planSync(analyticId, dir, patterns, deleteExtra)
+	if err != nil {
+		fmt.Printf("Failed to sync artifacts: %s\n", err)
+		panic(1)
+	}
+	p.ui.Ok()
+
+	table := p.ui.Table([]string{"Filename", "Type", "Action"})
+	changes, deletions := 0, 0
+	for _, action := range actions {
+		table.Add(action.Filename, action.Type, action.Action)
+		if action.run != nil {
+			changes++
+		}
+		if action.Action == "delete" {
+			deletions++
+		}
+	}
+	table.Print()
+	if changes == 0 {
+		p.ui.Say("Artifacts are up to date")
+		return
+	}
+	if dryRun {
+		p.ui.Say("Dry run, %d change(s) not applied", changes)
+		return
+	}
+	if deletions > 0 && !skipConfirm && !p.ui.Confirm(fmt.Sprintf("Really delete %d artifact(s) of analytic %s?", deletions, analyticName)) {
+		return
+	}
+	for _, action := range actions {
+		if action.run == nil {
+			continue
+		}
+		p.ui.Say("%s %s", action.Action, action.Filename)
+		if e := action.run(); e != nil {
+			fmt.Printf("Failed to %s artifact %s: %s\n", action.Action, action.Filename, e)
+			panic(1)
+		}
+		p.ui.Ok()
+	}
+	p.ui.Say("Synced %d artifact(s) of analytic %s", changes, analyticName)
+}
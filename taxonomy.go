@@ -19,7 +19,7 @@ func printTaxonomy(parent string, taxonomy Taxonomy) {
 
 }
 
-func (p *AnalyticsPlugin) getTaxonomy() {
+func (p *AnalyticsPlugin) taxonomyList() []Taxonomy {
 	r, e := p.client.Get().Path("/api/v1/catalog/taxonomy").Do()
 	if e != nil {
 		fmt.Printf("Failed to get taxonomy: %s\n", e)
@@ -27,22 +27,36 @@ func (p *AnalyticsPlugin) getTaxonomy() {
 	}
 	var taxonomy []Taxonomy
 	r.JSON(&taxonomy)
-	for _, t := range taxonomy {
+	return taxonomy
+}
+
+func (p *AnalyticsPlugin) getTaxonomy() {
+	for _, t := range p.taxonomyList() {
 		printTaxonomy("", t)
 	}
 }
 
-func (p *AnalyticsPlugin) addTaxonomy(t string) {
-	taxonomies := strings.Split(t, "/")
+func (p *AnalyticsPlugin) addTaxonomyTree(taxonomy Taxonomy) error {
+	r, e := p.client.Post().Path("/api/v1/catalog/taxonomy").JSON(taxonomy).Do()
+	return responseError(r, e)
+}
+
+// taxonomyFromPath builds the taxonomy branch for a location such as /a/b/c.
+func taxonomyFromPath(location string) Taxonomy {
 	var taxonomy Taxonomy
 	tt := &taxonomy
-	for _, t := range taxonomies {
+	for _, t := range strings.Split(location, "/") {
 		if t != "" {
 			tt.Name = t
 			tt.Childs = make([]Taxonomy, 1)
 			tt = &(tt.Childs[0])
 		}
 	}
+	return taxonomy
+}
+
+func (p *AnalyticsPlugin) addTaxonomy(t string) {
+	taxonomy := taxonomyFromPath(t)
 	fmt.Printf("Adding `%s` taxonomy...\n", t)
 	_, e := p.client.Post().Path("/api/v1/catalog/taxonomy").JSON(taxonomy).Do()
 	if e != nil {
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var semverPattern = regexp.MustCompile(`^[vV]?(\d+)(?:\.(\d+))?(?:\.(\d+))?$`)
+
+type semver struct {
+	Major, Minor, Patch int
+}
+
+func parseSemver(s string) (semver, bool) {
+	m := semverPattern.FindStringSubmatch(s)
+	if m == nil {
+		return semver{}, false
+	}
+	var v semver
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	return v, true
+}
+
+func (v semver) less(o semver) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	return v.Patch < o.Patch
+}
+
+func (v semver) bump(part string) (semver, error) {
+	switch part {
+	case "major":
+		return semver{v.Major + 1, 0, 0}, nil
+	case "minor":
+		return semver{v.Major, v.Minor + 1, 0}, nil
+	case "patch":
+		return semver{v.Major, v.Minor, v.Patch + 1}, nil
+	}
+	return v, fmt.Errorf("unknown version part %s, use major, minor or patch", part)
+}
+
+func (v semver) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// latestVersion returns the highest semantic version among the catalog
+// entries named analyticName. Versions that aren't semantic are ignored.
+func (p *AnalyticsPlugin) latestVersion(analyticName string) semver {
+	var latest semver
+	for _, analytic := range p.analyticsList() {
+		if analytic.Name != analyticName {
+			continue
+		}
+		if v, ok := parseSemver(analytic.Version); ok && latest.less(v) {
+			latest = v
+		}
+	}
+	return latest
+}
+
+func gitDescribe() (string, error) {
+	out, err := exec.Command("git", "describe", "--tags", "--always").Output()
+	if err != nil {
+		return "", fmt.Errorf("git describe failed: %s", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// analyticVersion works out the version of a new analytic: bumped from the
+// latest existing version, taken from git describe, or the given version. The
+// result has to match pattern if one is configured.
+func (p *AnalyticsPlugin) analyticVersion(analyticName, version, bump string, fromGit bool, pattern string) string {
+	var err error
+	switch {
+	case bump != "":
+		var v semver
+		v, err = p.latestVersion(analyticName).bump(bump)
+		version = v.String()
+	case fromGit:
+		version, err = gitDescribe()
+	}
+	if err != nil {
+		fmt.Printf("Failed to determine analytic version: %s\n", err)
+		panic(1)
+	}
+	if pattern == "" {
+		return version
+	}
+	matched, err := regexp.MatchString(pattern, version)
+	if err != nil {
+		fmt.Printf("Invalid version pattern: %s\n", err)
+		panic(1)
+	}
+	if !matched {
+		fmt.Printf("Version %s doesn't match pattern %s\n", version, pattern)
+		panic(1)
+	}
+	return version
+}
+
+// setVersionPattern shows the version pattern saved in the plugin config, or
+// replaces it with pattern. An empty pattern with unset removes it.
+func (p *AnalyticsPlugin) setVersionPattern(pattern string, unset bool) {
+	if pattern == "" && !unset {
+		if p.VersionPattern == "" {
+			p.ui.Say("No version pattern configured")
+		} else {
+			p.ui.Say("Version pattern: %s", p.VersionPattern)
+		}
+		return
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		fmt.Printf("Invalid version pattern: %s\n", err)
+		panic(1)
+	}
+	p.VersionPattern = pattern
+	p.saveConfig()
+	if unset {
+		p.ui.Say("Version pattern removed")
+	} else {
+		p.ui.Say("Versions of new analytics have to match %s", pattern)
+	}
+}